@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"go-pet-shop/internal/alert"
 	"go-pet-shop/internal/config"
 	"go-pet-shop/internal/handlers"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/internal/lib/idempotency"
 	"go-pet-shop/internal/lib/logger"
+	"go-pet-shop/internal/payment"
+	"go-pet-shop/internal/recommendation"
+	"go-pet-shop/internal/reservation"
+	"go-pet-shop/internal/segmentation"
 	"go-pet-shop/internal/storage/postgres"
+	"go-pet-shop/models"
 	"log/slog"
 	"net/http"
 	"os"
@@ -21,12 +31,25 @@ func main() {
 	log.Debug("debug messages are enabled")
 	log.Error("error messages are enabled")
 
-	storage, err := postgres.New(cfg.DatabaseURL)
+	storage, err := postgres.New(cfg.DatabaseURL, cfg.Reservation.TTL, cfg.Tax.DefaultRegion)
 	if err != nil {
 		log.Error("failed to init storage", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
+	privateKey, publicKey, err := auth.LoadKeys(cfg.Auth.PrivateKeyPath, cfg.Auth.PublicKeyPath)
+	if err != nil {
+		log.Error("failed to load JWT keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	tokens := auth.NewTokenManager(privateKey, publicKey, cfg.Auth.Issuer, cfg.Auth.TokenTTL)
+	authenticate := auth.Middleware(log, tokens)
+	authenticateOptional := auth.OptionalMiddleware(log, tokens)
+
+	adminOnly := auth.Authorize(auth.AnyRole(models.RoleAdmin))
+	staffOnly := auth.Authorize(auth.AnyRole(models.RoleAdmin, models.RoleStaff))
+	selfOrStaff := auth.Authorize(auth.SelfOrRole("email", models.RoleAdmin, models.RoleStaff))
+
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
@@ -35,33 +58,174 @@ func main() {
 	router.Use(logger.CustomLogger(log))
 
 	router.Get("/health", handlers.StatusHandler)
+	router.Post("/auth/login", handlers.Login(log, storage, tokens))
 
 	router.Route("/products", func(r chi.Router) {
-		r.Get("/", handlers.GetAllProducts(log, storage))
-		r.Post("/", handlers.CreateProduct(log, storage))
+		r.With(authenticateOptional).Get("/", handlers.GetAllProducts(log, storage, storage))
+		r.Get("/search", handlers.SearchProducts(log, storage))
 		r.Get("/{id}", handlers.GetProductByID(log, storage))
-		r.Put("/{id}", handlers.UpdateProduct(log, storage))
-		r.Delete("/{id}", handlers.DeleteProduct(log, storage))
 
 		r.Get("/popular", handlers.GetPopularProducts(log, storage))
+		r.Get("/{id}/reviews", handlers.GetProductReviews(log, storage))
+		r.Get("/{id}/recommendations", handlers.GetProductRecommendations(log, storage))
+		r.With(authenticate).Post("/{id}/reviews", handlers.CreateReview(log, storage))
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, adminOnly)
+
+			r.Get("/low-stock", handlers.GetLowStockProducts(log, storage))
+			r.Post("/", handlers.CreateProduct(log, storage))
+			r.Post("/import", handlers.ImportProducts(log, storage))
+			r.Put("/{id}", handlers.UpdateProduct(log, storage))
+			r.Delete("/{id}", handlers.DeleteProduct(log, storage))
 
+			r.Post("/{id}/variants", handlers.CreateProductVariant(log, storage))
+			r.Put("/{id}/variants/{variantID}", handlers.UpdateProductVariant(log, storage))
+			r.Delete("/{id}/variants/{variantID}", handlers.DeleteProductVariant(log, storage))
+		})
 
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, staffOnly)
+
+			r.Get("/{id}/inventory", handlers.GetInventoryMovements(log, storage))
+			r.Post("/{id}/inventory", handlers.AdjustInventory(log, storage))
+		})
 	})
 
-	ordersHandler := handlers.NewOrdersHandler(log, storage)
+	router.With(authenticate, staffOnly).Get("/inventory/reconciliation", handlers.ReconcileInventory(log, storage))
+
+	router.Route("/categories", func(r chi.Router) {
+		r.Get("/", handlers.GetAllCategories(log, storage))
+		r.Get("/species", handlers.GetAllSpecies(log, storage))
+		r.Get("/{id}", handlers.GetCategoryByID(log, storage))
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, adminOnly)
+
+			r.Post("/", handlers.CreateCategory(log, storage))
+			r.Put("/{id}", handlers.UpdateCategory(log, storage))
+			r.Delete("/{id}", handlers.DeleteCategory(log, storage))
+		})
+	})
+
+	router.Route("/tax/regions", func(r chi.Router) {
+		r.Get("/", handlers.GetTaxRegions(log, storage))
+		r.With(authenticate, adminOnly).Put("/{code}", handlers.SaveTaxRegion(log, storage))
+	})
+
+	router.Route("/reviews", func(r chi.Router) {
+		r.Use(authenticate)
+
+		r.Put("/{id}", handlers.UpdateReview(log, storage))
+		r.Delete("/{id}", handlers.DeleteReview(log, storage))
+		r.With(staffOnly).Get("/", handlers.GetReviewsForModeration(log, storage))
+		r.With(staffOnly).Post("/{id}/moderation", handlers.ModerateReview(log, storage))
+	})
+
+	router.Get("/shipping/methods", handlers.GetShippingMethods(log, storage))
+
+	router.Route("/analytics", func(r chi.Router) {
+		r.Use(authenticate)
+
+		r.With(adminOnly).Get("/revenue", handlers.GetRevenue(log, storage))
+		r.With(staffOnly).Get("/customers", handlers.GetCustomerSegments(log, storage))
+	})
+
+	router.Route("/coupons", func(r chi.Router) {
+		r.Use(authenticate, adminOnly)
+
+		r.Get("/", handlers.GetAllCoupons(log, storage))
+		r.Get("/{id}", handlers.GetCouponByID(log, storage))
+		r.Post("/", handlers.CreateCoupon(log, storage))
+		r.Put("/{id}", handlers.UpdateCoupon(log, storage))
+		r.Delete("/{id}", handlers.DeleteCoupon(log, storage))
+	})
+
+	gateway, err := newPaymentGateway(cfg.Payment)
+	if err != nil {
+		log.Error("failed to init payment gateway", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	payments := payment.NewProcessor(log, gateway, storage)
+	if fake, ok := gateway.(*payment.FakeGateway); ok {
+		fake.OnConfirm(func(c payment.Confirmation) {
+			if err := payments.Confirm(context.Background(), c); err != nil {
+				log.Error("failed to confirm payment", slog.String("reference", c.Reference), slog.String("error", err.Error()))
+			}
+		})
+	}
+
+	sweeper := reservation.NewSweeper(log, storage, payments, cfg.Reservation.SweepInterval)
+	go sweeper.Run(context.Background())
+
+	notifier, err := newAlertNotifier(log, cfg.Alerts)
+	if err != nil {
+		log.Error("failed to init alert notifier", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	lowStock := alert.NewLowStockJob(log, storage, notifier, cfg.Alerts.Interval)
+	go lowStock.Run(context.Background())
+
+	recommendations := recommendation.NewRefresher(log, storage, cfg.Recommendations.MinSupport, cfg.Recommendations.RefreshInterval)
+	go recommendations.Run(context.Background())
+
+	segments := segmentation.NewJob(log, storage, cfg.Segmentation.Window, cfg.Segmentation.NewWindow, cfg.Segmentation.Interval)
+	go segments.Run(context.Background())
+
+	ordersHandler := handlers.NewOrdersHandler(log, storage, payments)
 	router.Route("/orders", func(r chi.Router) {
-		r.Post("/", ordersHandler.CreateOrder)
-		r.Post("/{id}/items", ordersHandler.AddOrderItem)
-		r.Post("/place", ordersHandler.PlaceOrder)
+		r.Use(authenticate)
+
+		r.With(idempotency.Middleware(log, storage)).Post("/place", ordersHandler.PlaceOrder)
 		r.Get("/{id}", ordersHandler.GetOrderByID)
+		r.Post("/{id}/cancel", ordersHandler.CancelOrder)
+		r.With(staffOnly).Post("/{id}/transitions", ordersHandler.TransitionOrder)
+	})
+
+	cartsHandler := handlers.NewCartsHandler(log, storage, payments)
+	router.Route("/carts", func(r chi.Router) {
+		r.Use(authenticateOptional)
+
+		r.Post("/", cartsHandler.CreateCart)
+		r.Get("/{id}", cartsHandler.GetCart)
+		r.Post("/{id}/items", cartsHandler.AddCartItem)
+		r.Put("/{id}/items/{itemID}", cartsHandler.UpdateCartItem)
+		r.Delete("/{id}/items/{itemID}", cartsHandler.DeleteCartItem)
+		r.With(idempotency.Middleware(log, storage)).Post("/{id}/checkout", cartsHandler.Checkout)
 	})
 
 	router.Route("/users", func(r chi.Router) {
-		r.Get("/{email}/history", ordersHandler.GetUserOrderHistory)
 		r.Post("/", handlers.CreateUser(log, storage))
-		r.Get("/", handlers.GetAllUsers(log, storage))
-		r.Get("/{email}", handlers.GetUserByEmail(log, storage))
-		r.Get("/{email}/orders", ordersHandler.GetOrdersByUserEmail) 
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate)
+
+			r.With(staffOnly).Get("/", handlers.GetAllUsers(log, storage))
+			r.With(adminOnly).Put("/{email}/role", handlers.UpdateUserRole(log, storage))
+
+			r.With(selfOrStaff).Get("/{email}", handlers.GetUserByEmail(log, storage))
+			r.With(selfOrStaff).Get("/{email}/history", ordersHandler.GetUserOrderHistory)
+			r.With(selfOrStaff).Get("/{email}/orders", ordersHandler.GetOrdersByUserEmail)
+			r.With(selfOrStaff).Get("/{email}/recommendations", handlers.GetUserRecommendations(log, storage, storage, cfg.Recommendations.RecentWindow))
+
+			r.Route("/{email}/addresses", func(r chi.Router) {
+				r.Use(selfOrStaff)
+
+				r.Get("/", handlers.GetAddresses(log, storage))
+				r.Post("/", handlers.CreateAddress(log, storage))
+				r.Put("/{id}", handlers.UpdateAddress(log, storage))
+				r.Delete("/{id}", handlers.DeleteAddress(log, storage))
+			})
+
+			r.Route("/{email}/pets", func(r chi.Router) {
+				r.Use(selfOrStaff)
+
+				r.Get("/", handlers.GetPets(log, storage))
+				r.Post("/", handlers.CreatePet(log, storage))
+				r.Put("/{id}", handlers.UpdatePet(log, storage))
+				r.Delete("/{id}", handlers.DeletePet(log, storage))
+			})
+		})
 	})
 
 	handler := logger.LoggingMiddleware(log, router)
@@ -80,3 +244,26 @@ func main() {
 		log.Error("Server error: ", slog.String("err", err.Error()))
 	}
 }
+
+func newPaymentGateway(cfg config.Payment) (payment.Gateway, error) {
+	switch cfg.Provider {
+	case "fake":
+		return payment.NewFakeGateway(cfg.AsyncDelay), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+}
+
+func newAlertNotifier(log *slog.Logger, cfg config.Alerts) (alert.Notifier, error) {
+	var sinks alert.MultiNotifier
+	for _, sink := range cfg.Sinks {
+		switch sink {
+		case "log":
+			sinks = append(sinks, alert.NewLogNotifier(log))
+		case "file":
+			sinks = append(sinks, alert.NewFileNotifier(cfg.OutboxPath))
+		default:
+			return nil, fmt.Errorf("unknown alert sink %q", sink)
+		}
+	}
+	return sinks, nil
+}
@@ -0,0 +1,80 @@
+// Команда importer загружает каталог товаров из CSV или NDJSON так же,
+// как POST /products/import:
+//
+//	go run ./cmd/importer -file products.csv -dry-run
+//
+// Отчёт печатается в stdout в JSON. Код выхода 1, если есть отклонённые строки.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"go-pet-shop/internal/productimport"
+	"go-pet-shop/internal/storage/postgres"
+	"go-pet-shop/models"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	_ = godotenv.Load(".env")
+
+	var path, format string
+	var dryRun bool
+	flag.StringVar(&path, "file", "", "path to CSV or NDJSON file, - for stdin")
+	flag.StringVar(&format, "format", "", "csv or ndjson, by default taken from the file extension")
+	flag.BoolVar(&dryRun, "dry-run", false, "report what would change without saving")
+	flag.Parse()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL not set in environment")
+	}
+	if path == "" {
+		log.Fatal("file is required")
+	}
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "jsonl" {
+			format = string(models.ImportNDJSON)
+		}
+	}
+	if !models.ImportFormat(format).Valid() {
+		log.Fatal("format must be one of csv, ndjson")
+	}
+
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	storage, err := postgres.New(dbURL, 0, "")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer storage.Close()
+
+	report, err := productimport.Import(storage, in, models.ImportFormat(format), dryRun, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+
+	if report.Rejected > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,83 @@
+package alert
+
+import (
+	"context"
+	"go-pet-shop/models"
+	"log/slog"
+	"time"
+)
+
+type Store interface {
+	GetLowStockProducts() ([]models.LowStockProduct, error)
+	MarkLowStockAlerted(p models.LowStockProduct) error
+	ClearRestockedAlerts() (int64, error)
+}
+
+// LowStockJob периодически ищет товары с остатком ниже порога дозаказа
+// и оповещает о них через Notifier. По каждому товару оповещение уходит
+// один раз, следующее — только после пополнения остатка до порога.
+type LowStockJob struct {
+	log      *slog.Logger
+	store    Store
+	notifier Notifier
+	interval time.Duration
+}
+
+func NewLowStockJob(log *slog.Logger, store Store, notifier Notifier, interval time.Duration) *LowStockJob {
+	return &LowStockJob{log: log, store: store, notifier: notifier, interval: interval}
+}
+
+// Run проверяет остатки сразу и затем раз в interval до отмены ctx.
+func (j *LowStockJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Check(ctx); err != nil {
+			j.log.Error("low stock check failed", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check выполняет один проход и возвращает число отправленных оповещений.
+// Если оповещение не доставлено, товар не помечается и попадёт
+// в следующий проход.
+func (j *LowStockJob) Check(ctx context.Context) (int, error) {
+	cleared, err := j.store.ClearRestockedAlerts()
+	if err != nil {
+		return 0, err
+	}
+	if cleared > 0 {
+		j.log.Info("low stock alerts cleared after restock", slog.Int64("count", cleared))
+	}
+
+	products, err := j.store.GetLowStockProducts()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, p := range products {
+		if p.AlertedAt != nil {
+			continue
+		}
+
+		if err := j.notifier.Notify(ctx, p); err != nil {
+			j.log.Error("failed to send low stock alert", slog.Int("product_id", p.ProductID), slog.Any("error", err))
+			continue
+		}
+
+		if err := j.store.MarkLowStockAlerted(p); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,100 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-pet-shop/models"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Notifier доставляет оповещение о низком остатке. Реализации должны
+// возвращать ошибку, если оповещение не доставлено: тогда оно будет
+// повторено при следующем проходе LowStockJob.
+type Notifier interface {
+	Notify(ctx context.Context, p models.LowStockProduct) error
+}
+
+// LogNotifier пишет оповещения в лог приложения.
+type LogNotifier struct {
+	log *slog.Logger
+}
+
+func NewLogNotifier(log *slog.Logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, p models.LowStockProduct) error {
+	n.log.Warn("low stock",
+		slog.Int("product_id", p.ProductID),
+		slog.String("name", p.Name),
+		slog.Int("stock", p.Stock),
+		slog.Int("reorder_threshold", p.ReorderThreshold),
+	)
+	return nil
+}
+
+// FileNotifier дописывает оповещения в локальный outbox-файл по одному
+// JSON-объекту на строку. Файл забирает внешний процесс (почта, мессенджер).
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+type outboxRecord struct {
+	Type string `json:"type"`
+	models.LowStockProduct
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (n *FileNotifier) Notify(_ context.Context, p models.LowStockProduct) error {
+	const fn = "alert.FileNotifier.Notify"
+
+	line, err := json.Marshal(outboxRecord{Type: "low_stock", LowStockProduct: p, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.path), 0o755); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// MultiNotifier отправляет оповещение во все каналы. Ошибка хотя бы
+// одного канала возвращается, но остальные каналы всё равно вызываются.
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(ctx context.Context, p models.LowStockProduct) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
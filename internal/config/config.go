@@ -9,9 +9,16 @@ import (
 )
 
 type Config struct {
-	Env         string `yaml:"env" env-default:"local"`
-	DatabaseURL string `yaml:"database_url" env:"DATABASE_URL"`
-	HTTPServer  `yaml:"http_server"`
+	Env             string `yaml:"env" env-default:"local"`
+	DatabaseURL     string `yaml:"database_url" env:"DATABASE_URL"`
+	HTTPServer      `yaml:"http_server"`
+	Auth            `yaml:"auth"`
+	Payment         `yaml:"payment"`
+	Reservation     `yaml:"reservation"`
+	Alerts          `yaml:"alerts"`
+	Tax             `yaml:"tax"`
+	Recommendations `yaml:"recommendations"`
+	Segmentation    `yaml:"segmentation"`
 }
 
 type HTTPServer struct {
@@ -20,6 +27,50 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
 
+type Auth struct {
+	PrivateKeyPath string        `yaml:"private_key_path" env:"JWT_PRIVATE_KEY_PATH" env-default:"./config/keys/jwt.pem"`
+	PublicKeyPath  string        `yaml:"public_key_path" env:"JWT_PUBLIC_KEY_PATH" env-default:"./config/keys/jwt.pub.pem"`
+	Issuer         string        `yaml:"issuer" env-default:"go-pet-shop"`
+	TokenTTL       time.Duration `yaml:"token_ttl" env-default:"1h"`
+}
+
+type Payment struct {
+	Provider   string        `yaml:"provider" env:"PAYMENT_PROVIDER" env-default:"fake"`
+	AsyncDelay time.Duration `yaml:"async_delay" env-default:"2s"` // только для fake
+}
+
+// Reservation — резервирование остатков на время оплаты заказа.
+type Reservation struct {
+	TTL           time.Duration `yaml:"ttl" env:"RESERVATION_TTL" env-default:"15m"`
+	SweepInterval time.Duration `yaml:"sweep_interval" env-default:"30s"`
+}
+
+// Alerts — оповещения о низком остатке.
+type Alerts struct {
+	Interval   time.Duration `yaml:"interval" env-default:"5m"`
+	Sinks      []string      `yaml:"sinks" env:"ALERT_SINKS" env-default:"log"` // log, file
+	OutboxPath string        `yaml:"outbox_path" env-default:"./storage/alerts.ndjson"`
+}
+
+// Tax — налоги заказов.
+type Tax struct {
+	DefaultRegion string `yaml:"default_region" env:"TAX_DEFAULT_REGION" env-default:"RU"` // для заказов без tax_region
+}
+
+// Recommendations — рекомендации «покупают вместе».
+type Recommendations struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval" env-default:"1h"`
+	MinSupport      int           `yaml:"min_support" env-default:"2"`      // минимум совместных заказов для пары
+	RecentWindow    time.Duration `yaml:"recent_window" env-default:"720h"` // купленное за это время не рекомендуется повторно
+}
+
+// Segmentation — RFM-сегментация покупателей.
+type Segmentation struct {
+	Interval  time.Duration `yaml:"interval" env-default:"6h"`
+	Window    time.Duration `yaml:"window" env-default:"8760h"`    // окно для frequency и monetary
+	NewWindow time.Duration `yaml:"new_window" env-default:"720h"` // первый заказ моложе — сегмент new
+}
+
 const configPath = "./config/local.yaml"
 
 func MustLoad() *Config {
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Addresses interface {
+	GetAddresses(email string) ([]models.Address, error)
+	CreateAddress(email string, address models.Address) (models.Address, error)
+	UpdateAddress(email string, address models.Address) (models.Address, error)
+	DeleteAddress(email string, id int) error
+}
+
+type Shipping interface {
+	GetShippingMethods() ([]models.ShippingMethod, error)
+}
+
+// addressErrorStatus подбирает HTTP-статус для ошибок storage по адресам.
+func addressErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound), errors.Is(err, storage.ErrAddressNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeAddress читает адрес из тела запроса и проверяет его.
+func decodeAddress(w http.ResponseWriter, r *http.Request) (models.Address, bool) {
+	var address models.Address
+	if err := render.DecodeJSON(r.Body, &address); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return address, false
+	}
+
+	address.Normalize()
+	if err := address.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return address, false
+	}
+
+	return address, true
+}
+
+// GetAddresses возвращает адресную книгу пользователя {email}.
+func GetAddresses(log *slog.Logger, addresses Addresses) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.addresses.GetAddresses"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		list, err := addresses.GetAddresses(chi.URLParam(r, "email"))
+		if err != nil {
+			status := addressErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to get addresses", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.JSON(w, r, list)
+	}
+}
+
+func CreateAddress(log *slog.Logger, addresses Addresses) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.addresses.CreateAddress"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		address, ok := decodeAddress(w, r)
+		if !ok {
+			return
+		}
+
+		address, err := addresses.CreateAddress(chi.URLParam(r, "email"), address)
+		if err != nil {
+			status := addressErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to create address", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, address)
+	}
+}
+
+func UpdateAddress(log *slog.Logger, addresses Addresses) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.addresses.UpdateAddress"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid address ID", http.StatusBadRequest)
+			return
+		}
+
+		address, ok := decodeAddress(w, r)
+		if !ok {
+			return
+		}
+		address.ID = id
+
+		address, err = addresses.UpdateAddress(chi.URLParam(r, "email"), address)
+		if err != nil {
+			status := addressErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to update address", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.JSON(w, r, address)
+	}
+}
+
+func DeleteAddress(log *slog.Logger, addresses Addresses) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.addresses.DeleteAddress"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid address ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := addresses.DeleteAddress(chi.URLParam(r, "email"), id); err != nil {
+			status := addressErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to delete address", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Address deleted successfully"})
+	}
+}
+
+// GetShippingMethods возвращает способы доставки и их тарифы по зонам.
+func GetShippingMethods(log *slog.Logger, shipping Shipping) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.addresses.GetShippingMethods"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		methods, err := shipping.GetShippingMethods()
+		if err != nil {
+			log.Error("failed to get shipping methods", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, methods)
+	}
+}
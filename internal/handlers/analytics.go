@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Analytics interface {
+	GetRevenue(q models.RevenueQuery) (models.RevenueReport, error)
+	GetCustomerSegments(q models.CustomerRFMQuery) ([]models.CustomerRFM, error)
+}
+
+const (
+	defaultRevenueDays = 30
+	maxRevenueBuckets  = 400
+
+	defaultCustomerLimit = 50
+	maxCustomerLimit     = 500
+)
+
+// parseRevenueQuery читает from, to, granularity и tz. По умолчанию —
+// последние 30 дней по дням в UTC.
+func parseRevenueQuery(r *http.Request) (models.RevenueQuery, error) {
+	params := r.URL.Query()
+	query := models.RevenueQuery{Granularity: models.Granularity(params.Get("granularity"))}
+
+	if query.Granularity == "" {
+		query.Granularity = models.GranularityDay
+	}
+	if !query.Granularity.Valid() {
+		return query, errors.New("granularity must be one of day, week, month")
+	}
+
+	var err error
+	query.From, query.To, query.Timezone, err = parseDateRange(params, defaultRevenueDays)
+	if err != nil {
+		return query, err
+	}
+	if revenueBuckets(query) > maxRevenueBuckets {
+		return query, fmt.Errorf("range is too long: at most %d %ss", maxRevenueBuckets, query.Granularity)
+	}
+
+	return query, nil
+}
+
+// parseDateRange читает from и to (YYYY-MM-DD, включительно) и часовой пояс
+// tz, по умолчанию UTC. Без to берётся сегодняшний день в tz, без from —
+// defaultDays дней до to.
+func parseDateRange(params url.Values, defaultDays int) (from, to time.Time, tz string, err error) {
+	tz = params.Get("tz")
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return from, to, tz, errors.New("invalid tz")
+	}
+
+	now := time.Now().In(loc)
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := params.Get("to"); v != "" {
+		if to, err = time.Parse(time.DateOnly, v); err != nil {
+			return from, to, tz, errors.New("to must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	from = to.AddDate(0, 0, -(defaultDays - 1))
+	if v := params.Get("from"); v != "" {
+		if from, err = time.Parse(time.DateOnly, v); err != nil {
+			return from, to, tz, errors.New("from must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	if from.After(to) {
+		return from, to, tz, errors.New("from must not be after to")
+	}
+	return from, to, tz, nil
+}
+
+// revenueBuckets оценивает сверху число периодов в отчёте.
+func revenueBuckets(q models.RevenueQuery) int {
+	days := int(q.To.Sub(q.From).Hours()/24) + 1
+	switch q.Granularity {
+	case models.GranularityWeek:
+		return days/7 + 2
+	case models.GranularityMonth:
+		return (q.To.Year()-q.From.Year())*12 + int(q.To.Month()-q.From.Month()) + 1
+	}
+	return days
+}
+
+// GetRevenue возвращает выручку, число заказов, средний чек и проданные
+// единицы по периодам. Учитываются оплаченные заказы.
+func GetRevenue(log *slog.Logger, analytics Analytics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.analytics.GetRevenue"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query, err := parseRevenueQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := analytics.GetRevenue(query)
+		if err != nil {
+			log.Error("failed to get revenue", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, report)
+	}
+}
+
+// parseCustomerRFMQuery читает segment, limit и offset.
+func parseCustomerRFMQuery(r *http.Request) (models.CustomerRFMQuery, error) {
+	params := r.URL.Query()
+	query := models.CustomerRFMQuery{
+		Segment: models.CustomerSegment(params.Get("segment")),
+		Limit:   defaultCustomerLimit,
+	}
+
+	if query.Segment != "" && !query.Segment.Valid() {
+		return query, errors.New("segment must be one of champion, at-risk, lost, new, regular")
+	}
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxCustomerLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxCustomerLimit)
+		}
+		query.Limit = limit
+	}
+
+	if v := params.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return query, errors.New("invalid offset")
+		}
+		query.Offset = offset
+	}
+
+	return query, nil
+}
+
+// GetCustomerSegments возвращает покупателей с RFM-оценками и сегментом,
+// самые ценные первыми. Оценки обновляет фоновая задача, см. computed_at.
+func GetCustomerSegments(log *slog.Logger, analytics Analytics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.analytics.GetCustomerSegments"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query, err := parseCustomerRFMQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		customers, err := analytics.GetCustomerSegments(query)
+		if err != nil {
+			log.Error("failed to get customer segments", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, customers)
+	}
+}
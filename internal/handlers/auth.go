@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5"
+)
+
+type TokenIssuer interface {
+	Issue(id auth.Identity) (string, error)
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+}
+
+func Login(log *slog.Logger, users Users, tokens TokenIssuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.Login"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req loginRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", slog.Any("err", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := users.GetUserByEmail(req.Email)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Error("failed to get user", slog.Any("err", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := checkCredentials(user, req.Password); err != nil {
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			log.Error("failed to check password", slog.Any("err", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		token, err := tokens.Issue(auth.Identity{UserID: user.ID, Email: user.Email, Role: user.Role})
+		if err != nil {
+			log.Error("failed to issue token", slog.Any("err", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, loginResponse{AccessToken: token, TokenType: "Bearer"})
+	}
+}
+
+// checkCredentials для неизвестного email возвращает ту же ошибку, что и для
+// неверного пароля, чтобы по ответу нельзя было перебирать зарегистрированные адреса.
+func checkCredentials(user models.User, password string) error {
+	if user.Email == "" {
+		return auth.ErrInvalidCredentials
+	}
+	return auth.CheckPassword(user.PasswordHash, password)
+}
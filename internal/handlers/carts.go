@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Carts interface {
+	CreateCart(userID *int) (models.Cart, error)
+	GetCart(cartID string) (models.Cart, error)
+	GetCartView(cartID string) (models.CartView, error)
+	AddCartItem(item models.CartItem) (models.CartItem, error)
+	UpdateCartItem(cartID string, itemID, quantity int) error
+	DeleteCartItem(cartID string, itemID int) error
+	CheckoutCart(cartID string, userID int, draft models.OrderDraft) (int, error)
+}
+
+// CartsHandler обслуживает /carts. Маршруты работают и без входа:
+// анонимная корзина доступна по её ID, оформление требует токен.
+type CartsHandler struct {
+	log      *slog.Logger
+	Storage  Carts
+	Payments Payments
+}
+
+func NewCartsHandler(log *slog.Logger, storage Carts, payments Payments) *CartsHandler {
+	return &CartsHandler{
+		log:      log,
+		Storage:  storage,
+		Payments: payments,
+	}
+}
+
+type cartItemRequest struct {
+	ProductID int  `json:"product_id"`
+	VariantID *int `json:"variant_id"`
+	Quantity  int  `json:"quantity"`
+}
+
+type checkoutRequest struct {
+	CouponCode     string `json:"coupon_code"`
+	TaxRegion      string `json:"tax_region"`
+	AddressID      *int   `json:"address_id"`
+	ShippingMethod string `json:"shipping_method"`
+	PetID          *int   `json:"pet_id"`
+	PaymentToken   string `json:"payment_token"`
+}
+
+// ownCart загружает корзину из URL и проверяет доступ к ней.
+// Чужая корзина выглядит так же, как несуществующая.
+func (h *CartsHandler) ownCart(w http.ResponseWriter, r *http.Request) (models.Cart, bool) {
+	cartID := chi.URLParam(r, "id")
+	if !validCartID(cartID) {
+		http.Error(w, "cart not found", http.StatusNotFound)
+		return models.Cart{}, false
+	}
+
+	cart, err := h.Storage.GetCart(cartID)
+	if errors.Is(err, storage.ErrCartNotFound) {
+		http.Error(w, "cart not found", http.StatusNotFound)
+		return models.Cart{}, false
+	}
+	if err != nil {
+		h.log.Error("failed to get cart", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return models.Cart{}, false
+	}
+
+	id, authenticated := auth.IdentityFromContext(r.Context())
+	if !auth.CanAccessCart(id, authenticated, cart.UserID) {
+		http.Error(w, "cart not found", http.StatusNotFound)
+		return models.Cart{}, false
+	}
+
+	return cart, true
+}
+
+// CreateCart создаёт корзину вошедшего пользователя или анонимную.
+// ID анонимной корзины клиент должен сохранить — это единственный ключ к ней.
+func (h *CartsHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
+	var userID *int
+	if id, ok := auth.IdentityFromContext(r.Context()); ok {
+		userID = &id.UserID
+	}
+
+	cart, err := h.Storage.CreateCart(userID)
+	if err != nil {
+		h.log.Error("failed to create cart", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewCartView(cart, nil))
+}
+
+// GetCart возвращает корзину с актуальными ценами и предупреждениями
+// о нехватке остатков.
+func (h *CartsHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	cart, ok := h.ownCart(w, r)
+	if !ok {
+		return
+	}
+
+	h.renderCart(w, r, cart.ID, http.StatusOK)
+}
+
+func (h *CartsHandler) AddCartItem(w http.ResponseWriter, r *http.Request) {
+	cart, ok := h.ownCart(w, r)
+	if !ok {
+		return
+	}
+
+	var req cartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity <= 0 {
+		http.Error(w, "quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.Storage.AddCartItem(models.CartItem{
+		CartID:    cart.ID,
+		ProductID: req.ProductID,
+		VariantID: req.VariantID,
+		Quantity:  req.Quantity,
+	})
+	if err != nil {
+		h.cartError(w, "failed to add cart item", err)
+		return
+	}
+
+	h.renderCart(w, r, cart.ID, http.StatusCreated)
+}
+
+func (h *CartsHandler) UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	cart, ok := h.ownCart(w, r)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		http.Error(w, "invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity <= 0 {
+		http.Error(w, "quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Storage.UpdateCartItem(cart.ID, itemID, req.Quantity); err != nil {
+		h.cartError(w, "failed to update cart item", err)
+		return
+	}
+
+	h.renderCart(w, r, cart.ID, http.StatusOK)
+}
+
+func (h *CartsHandler) DeleteCartItem(w http.ResponseWriter, r *http.Request) {
+	cart, ok := h.ownCart(w, r)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		http.Error(w, "invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Storage.DeleteCartItem(cart.ID, itemID); err != nil {
+		h.cartError(w, "failed to delete cart item", err)
+		return
+	}
+
+	h.renderCart(w, r, cart.ID, http.StatusOK)
+}
+
+// Checkout оформляет корзину в заказ вошедшего пользователя и проводит
+// оплату. Коды ответа те же, что у POST /orders/place.
+func (h *CartsHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	caller, ok := auth.IdentityFromContext(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cart, ok := h.ownCart(w, r)
+	if !ok {
+		return
+	}
+
+	var req checkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	orderID, err := h.Storage.CheckoutCart(cart.ID, caller.UserID, models.OrderDraft{
+		CouponCode:     req.CouponCode,
+		TaxRegion:      req.TaxRegion,
+		AddressID:      req.AddressID,
+		ShippingMethod: req.ShippingMethod,
+		PetID:          req.PetID,
+	})
+	if err != nil {
+		h.cartError(w, "failed to check out cart", err)
+		return
+	}
+
+	h.log.Info("cart checked out", slog.String("cart_id", cart.ID), slog.Int("order_id", orderID))
+
+	payOrder(h.log, h.Payments, w, r, orderID, req.PaymentToken)
+}
+
+func (h *CartsHandler) renderCart(w http.ResponseWriter, r *http.Request, cartID string, status int) {
+	view, err := h.Storage.GetCartView(cartID)
+	if err != nil {
+		h.cartError(w, "failed to get cart", err)
+		return
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, view)
+}
+
+func (h *CartsHandler) cartError(w http.ResponseWriter, msg string, err error) {
+	status := cartErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		h.log.Error(msg, slog.Any("error", err))
+		http.Error(w, "internal error", status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrCartNotFound),
+		errors.Is(err, storage.ErrCartItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrCartClosed):
+		return http.StatusConflict
+	}
+	return placeOrderErrorStatus(err)
+}
+
+// validCartID проверяет, что ID корзины — UUID в каноническом виде,
+// чтобы мусор из URL не доходил до базы.
+func validCartID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
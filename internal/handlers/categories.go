@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Categories interface {
+	GetAllCategories() ([]models.Category, error)
+	GetCategoryByID(id int) (models.Category, error)
+	CreateCategory(category models.Category) (int, error)
+	UpdateCategory(category models.Category) error
+	DeleteCategory(id int) error
+	GetAllSpecies() ([]models.SpeciesInfo, error)
+}
+
+// categoryErrorStatus подбирает HTTP-статус для ошибок storage по категориям.
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrCategoryHasChildren), errors.Is(err, storage.ErrCategoryExists):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrCategoryCycle):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func categoryID(r *http.Request) (int, error) {
+	return strconv.Atoi(chi.URLParam(r, "id"))
+}
+
+func GetAllCategories(log *slog.Logger, categories Categories) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.categories.GetAllCategories"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		all, err := categories.GetAllCategories()
+		if err != nil {
+			log.Error("failed to get categories", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, all)
+	}
+}
+
+func GetCategoryByID(log *slog.Logger, categories Categories) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.categories.GetCategoryByID"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := categoryID(r)
+		if err != nil {
+			http.Error(w, "invalid category ID", http.StatusBadRequest)
+			return
+		}
+
+		category, err := categories.GetCategoryByID(id)
+		if err != nil {
+			status := categoryErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to get category", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.JSON(w, r, category)
+	}
+}
+
+func CreateCategory(log *slog.Logger, categories Categories) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.categories.CreateCategory"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var category models.Category
+		if err := render.DecodeJSON(r.Body, &category); err != nil {
+			log.Error("failed to decode request body", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if category.Name == "" || category.Slug == "" {
+			http.Error(w, "name and slug are required", http.StatusBadRequest)
+			return
+		}
+
+		id, err := categories.CreateCategory(category)
+		if err != nil {
+			status := categoryErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to create category", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, map[string]int{"id": id})
+	}
+}
+
+func UpdateCategory(log *slog.Logger, categories Categories) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.categories.UpdateCategory"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := categoryID(r)
+		if err != nil {
+			http.Error(w, "invalid category ID", http.StatusBadRequest)
+			return
+		}
+
+		var category models.Category
+		if err := render.DecodeJSON(r.Body, &category); err != nil {
+			log.Error("failed to decode request body", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		category.ID = id
+
+		if category.Name == "" || category.Slug == "" {
+			http.Error(w, "name and slug are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := categories.UpdateCategory(category); err != nil {
+			status := categoryErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to update category", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Category updated successfully"})
+	}
+}
+
+func DeleteCategory(log *slog.Logger, categories Categories) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.categories.DeleteCategory"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := categoryID(r)
+		if err != nil {
+			http.Error(w, "invalid category ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := categories.DeleteCategory(id); err != nil {
+			status := categoryErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to delete category", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Category deleted successfully"})
+	}
+}
+
+func GetAllSpecies(log *slog.Logger, categories Categories) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.categories.GetAllSpecies"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		species, err := categories.GetAllSpecies()
+		if err != nil {
+			log.Error("failed to get species", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, species)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Coupons interface {
+	GetAllCoupons() ([]models.Coupon, error)
+	GetCouponByID(id int) (models.Coupon, error)
+	CreateCoupon(coupon models.Coupon) (int, error)
+	UpdateCoupon(coupon models.Coupon) error
+	DeleteCoupon(id int) error
+}
+
+// couponErrorStatus подбирает HTTP-статус для ошибок storage по купонам.
+func couponErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidCoupon):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrCouponNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrCouponExists):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrProductNotFound):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func couponID(r *http.Request) (int, error) {
+	return strconv.Atoi(chi.URLParam(r, "id"))
+}
+
+func GetAllCoupons(log *slog.Logger, coupons Coupons) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.coupons.GetAllCoupons"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		all, err := coupons.GetAllCoupons()
+		if err != nil {
+			log.Error("failed to get coupons", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, all)
+	}
+}
+
+func GetCouponByID(log *slog.Logger, coupons Coupons) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.coupons.GetCouponByID"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := couponID(r)
+		if err != nil {
+			http.Error(w, "invalid coupon ID", http.StatusBadRequest)
+			return
+		}
+
+		coupon, err := coupons.GetCouponByID(id)
+		if err != nil {
+			status := couponErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to get coupon", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.JSON(w, r, coupon)
+	}
+}
+
+// CreateCoupon заводит промокод. Код хранится в верхнем регистре.
+func CreateCoupon(log *slog.Logger, coupons Coupons) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.coupons.CreateCoupon"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var coupon models.Coupon
+		if err := render.DecodeJSON(r.Body, &coupon); err != nil {
+			log.Error("failed to decode request body", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		coupon.Normalize()
+		if err := coupon.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id, err := coupons.CreateCoupon(coupon)
+		if err != nil {
+			status := couponErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to create coupon", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, map[string]int{"id": id})
+	}
+}
+
+func UpdateCoupon(log *slog.Logger, coupons Coupons) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.coupons.UpdateCoupon"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := couponID(r)
+		if err != nil {
+			http.Error(w, "invalid coupon ID", http.StatusBadRequest)
+			return
+		}
+
+		var coupon models.Coupon
+		if err := render.DecodeJSON(r.Body, &coupon); err != nil {
+			log.Error("failed to decode request body", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		coupon.ID = id
+
+		coupon.Normalize()
+		if err := coupon.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := coupons.UpdateCoupon(coupon); err != nil {
+			status := couponErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to update coupon", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Coupon updated successfully"})
+	}
+}
+
+func DeleteCoupon(log *slog.Logger, coupons Coupons) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.coupons.DeleteCoupon"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := couponID(r)
+		if err != nil {
+			http.Error(w, "invalid coupon ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := coupons.DeleteCoupon(id); err != nil {
+			status := couponErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to delete coupon", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Coupon deleted successfully"})
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"go-pet-shop/internal/lib/auth"
 	"go-pet-shop/internal/storage"
 	"go-pet-shop/models"
 	"log/slog"
@@ -9,6 +11,7 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5"
 )
 
 type Customers interface {
@@ -17,11 +20,12 @@ type Customers interface {
 	GetAllCustomers() ([]models.Customer, error)
 }
 
-// type Users interface {
-// 	CreateUser(user models.User) error
-// 	GetUserByEmail(email string) (models.User, error)
-// 	GetAllUsers() ([]models.User, error)
-// }
+type Users interface {
+	CreateUser(user models.User) error
+	GetUserByEmail(email string) (models.User, error)
+	GetAllUsers() ([]models.User, error)
+	UpdateUserRole(email string, role models.Role) error
+}
 
 
 func CreateCustomer(log *slog.Logger, customers Customers) http.HandlerFunc {
@@ -111,10 +115,31 @@ func GetUserByEmail(log *slog.Logger, storage storage.Storage) http.HandlerFunc
 			return
 		}
 
-		render.JSON(w, r, user)
+		// RFM-оценки — внутренняя маркетинговая информация, покупателю
+		// они не показываются.
+		caller, _ := auth.IdentityFromContext(r.Context())
+		if !auth.CanViewCustomerSegments(caller) {
+			render.JSON(w, r, user)
+			return
+		}
+
+		rfm, err := storage.GetCustomerRFM(user.ID)
+		if err != nil {
+			log.Error("failed to get customer rfm", slog.Any("err", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, userWithRFM{User: user, RFM: rfm})
 	}
 }
 
+// userWithRFM — пользователь вместе с его RFM-оценками для персонала.
+type userWithRFM struct {
+	models.User
+	RFM *models.CustomerRFM `json:"rfm"`
+}
+
 func CreateUser(log *slog.Logger, storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.users.CreateUser"
@@ -131,6 +156,22 @@ func CreateUser(log *slog.Logger, storage storage.Storage) http.HandlerFunc {
 			return
 		}
 
+		if user.Password == "" {
+			http.Error(w, "password is required", http.StatusBadRequest)
+			return
+		}
+
+		hash, err := auth.HashPassword(user.Password)
+		if err != nil {
+			log.Error("failed to hash password", slog.Any("err", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		user.PasswordHash = hash
+		user.Password = ""
+		// Регистрация всегда создаёт покупателя, роль меняет только админ.
+		user.Role = models.RoleCustomer
+
 		if err := storage.CreateUser(user); err != nil {
 			log.Error("failed to create user", slog.Any("err", err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -161,3 +202,47 @@ func GetAllUsers(log *slog.Logger, storage storage.Storage) http.HandlerFunc {
 	}
 }
 
+type roleRequest struct {
+	Role models.Role `json:"role"`
+}
+
+func UpdateUserRole(log *slog.Logger, users Users) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.users.UpdateUserRole"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		email := chi.URLParam(r, "email")
+		if email == "" {
+			http.Error(w, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		var req roleRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", slog.Any("err", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !req.Role.Valid() {
+			http.Error(w, "unknown role", http.StatusBadRequest)
+			return
+		}
+
+		if err := users.UpdateUserRole(email, req.Role); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, "user not found", http.StatusNotFound)
+				return
+			}
+			log.Error("failed to update user role", slog.Any("err", err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "role updated"})
+	}
+}
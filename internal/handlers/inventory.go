@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Inventory interface {
+	GetInventoryMovements(productID int) ([]models.InventoryMovement, error)
+	AdjustInventory(movement models.InventoryMovement) (models.InventoryMovement, error)
+	ReconcileInventory() ([]models.InventoryDiscrepancy, error)
+	GetLowStockProducts() ([]models.LowStockProduct, error)
+}
+
+// actorID возвращает ID вошедшего пользователя для записи в журналы
+// или 0, если запрос анонимный.
+func actorID(r *http.Request) int {
+	id, _ := auth.IdentityFromContext(r.Context())
+	return id.UserID
+}
+
+// GetInventoryMovements возвращает журнал движений остатков товара
+// и его вариантов.
+func GetInventoryMovements(log *slog.Logger, inventory Inventory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.inventory.GetInventoryMovements"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid product ID", http.StatusBadRequest)
+			return
+		}
+
+		movements, err := inventory.GetInventoryMovements(productID)
+		if err != nil {
+			log.Error("failed to get inventory movements", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, movements)
+	}
+}
+
+type adjustInventoryRequest struct {
+	VariantID   *int                   `json:"variant_id"`
+	Delta       int                    `json:"delta"`
+	Reason      models.InventoryReason `json:"reason"`
+	ReferenceID *int                   `json:"reference_id"`
+	Comment     string                 `json:"comment"`
+}
+
+// AdjustInventory записывает ручное движение остатка: поступление,
+// возврат или корректировку после инвентаризации.
+func AdjustInventory(log *slog.Logger, inventory Inventory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.inventory.AdjustInventory"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid product ID", http.StatusBadRequest)
+			return
+		}
+
+		var req adjustInventoryRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if req.Delta == 0 {
+			http.Error(w, "delta must not be zero", http.StatusBadRequest)
+			return
+		}
+		if !req.Reason.Manual() {
+			http.Error(w, "reason must be one of restock, return, adjustment", http.StatusBadRequest)
+			return
+		}
+
+		actor := actorID(r)
+		movement, err := inventory.AdjustInventory(models.InventoryMovement{
+			ProductID:   productID,
+			VariantID:   req.VariantID,
+			Delta:       req.Delta,
+			Reason:      req.Reason,
+			ReferenceID: req.ReferenceID,
+			ActorID:     &actor,
+			Comment:     req.Comment,
+		})
+		switch {
+		case errors.Is(err, storage.ErrProductNotFound), errors.Is(err, storage.ErrVariantNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, storage.ErrOutOfStock):
+			http.Error(w, "stock cannot become negative", http.StatusConflict)
+			return
+		case err != nil:
+			log.Error("failed to adjust inventory", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, movement)
+	}
+}
+
+// ReconcileInventory сверяет остатки с журналом движений. Пустой список
+// означает, что расхождений нет.
+func ReconcileInventory(log *slog.Logger, inventory Inventory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.inventory.ReconcileInventory"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		discrepancies, err := inventory.ReconcileInventory()
+		if err != nil {
+			log.Error("failed to reconcile inventory", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if len(discrepancies) > 0 {
+			log.Warn("inventory ledger does not match stock", slog.Int("discrepancies", len(discrepancies)))
+		}
+
+		render.JSON(w, r, map[string]any{
+			"consistent":    len(discrepancies) == 0,
+			"discrepancies": discrepancies,
+		})
+	}
+}
+
+// GetLowStockProducts возвращает товары с остатком ниже порога дозаказа.
+func GetLowStockProducts(log *slog.Logger, inventory Inventory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.inventory.GetLowStockProducts"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		products, err := inventory.GetLowStockProducts()
+		if err != nil {
+			log.Error("failed to get low stock products", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, products)
+	}
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/internal/payment"
+	"go-pet-shop/internal/storage"
 	"go-pet-shop/models"
 	"log/slog"
 	"net/http"
@@ -12,98 +17,152 @@ import (
 )
 
 type Orders interface {
-	CreateOrder(order models.Order) (int, error)
 	GetOrderByID(id int) (models.Order, error)
-	AddOrderItem(item models.OrderItem) error
 	GetOrderItemsByOrderID(orderID int) ([]models.OrderItem, error)
 	GetOrdersByUserEmail(email string) ([]models.Order, error)
-	PlaceOrder(userEmail string, items []models.OrderItem) (int, error)
+	PlaceOrder(userEmail string, draft models.OrderDraft) (int, error)
+	GetOrderDiscounts(orderID int) ([]models.OrderDiscount, error)
+	GetOrderTaxLines(orderID int) ([]models.OrderTaxLine, error)
+	GetOrderShipment(orderID int) (*models.OrderShipment, error)
 	GetUserOrderHistory(email string) ([]models.OrderDetail, error)
+	TransitionOrder(orderID int, to models.OrderStatus, actorID int, comment string) error
+	GetOrderStatusHistory(orderID int) ([]models.OrderStatusChange, error)
+	CancelOrder(orderID, actorID int) (alreadyCancelled bool, err error)
+	RefundOrder(orderID, actorID int, comment string) (alreadyRefunded bool, err error)
+}
+
+// Payments проводит оплату заказа, см. payment.Processor.
+type Payments interface {
+	Pay(ctx context.Context, orderID int, token string) (models.Payment, error)
+	Release(ctx context.Context, orderID int) error
 }
 
 type OrdersHandler struct {
 	log *slog.Logger
 	Storage Orders
-	
+	Payments Payments
 }
 
+// orderRequest больше не содержит user_email: заказ всегда оформляется
+// на пользователя из токена.
 type orderRequest struct {
-    UserEmail string             `json:"user_email"`
-    Items     []models.OrderItem `json:"items"`
+    models.OrderDraft
+    PaymentToken string `json:"payment_token"`
 }
 
-func NewOrdersHandler(log *slog.Logger, storage Orders) *OrdersHandler {
+func NewOrdersHandler(log *slog.Logger, storage Orders, payments Payments) *OrdersHandler {
 	return &OrdersHandler{
-		log:     log,
-		Storage: storage,
+		log:      log,
+		Storage:  storage,
+		Payments: payments,
 	}
 }
 
-func (h *OrdersHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
-	var order models.Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	id, err := h.Storage.CreateOrder(order)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// identity возвращает пользователя из контекста запроса. Если auth.Middleware
+// не был подключён к маршруту, отвечает 401.
+func (h *OrdersHandler) identity(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	id, ok := auth.IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 	}
-
-	w.WriteHeader(http.StatusCreated)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]int{"id": id})
+	return id, ok
 }
 
-func (h *OrdersHandler) AddOrderItem(w http.ResponseWriter, r *http.Request) {
-	orderIDStr := chi.URLParam(r, "id")
-	orderID, err := strconv.Atoi(orderIDStr)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// ownOrder загружает заказ и проверяет, что вызывающий может с ним работать:
+// покупатель — только со своими заказами, персонал — с любыми.
+// Недоступный заказ выглядит так же, как несуществующий.
+func (h *OrdersHandler) ownOrder(w http.ResponseWriter, r *http.Request, orderID int) (models.Order, bool) {
+	id, ok := h.identity(w, r)
+	if !ok {
+		return models.Order{}, false
 	}
 
-	var item models.OrderItem
-	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-		h.log.Error("failed to decode request body", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	order, err := h.Storage.GetOrderByID(orderID)
+	if err != nil {
+		h.log.Error("failed to get order", slog.Any("error", err))
+		http.Error(w, "order not found", http.StatusNotFound)
+		return models.Order{}, false
 	}
 
-	item.OrderID = orderID
-
-	if err := h.Storage.AddOrderItem(item); err != nil {
-		h.log.Error("failed to add order item", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if order.ID == 0 || !auth.CanAccessOrder(id, order.CustomerID) {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return models.Order{}, false
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "Order item added successfully"})
+	return order, true
 }
 
 func (h *OrdersHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+    id, ok := h.identity(w, r)
+    if !ok {
+        return
+    }
+
     var req orderRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
         http.Error(w, "invalid request", http.StatusBadRequest)
         return
     }
 
-    h.log.Info("PlaceOrder request", slog.Any("user_email", req.UserEmail), slog.Any("items", req.Items))
+    h.log.Info("PlaceOrder request", slog.Any("user_email", id.Email), slog.Any("items", req.Items), slog.String("coupon_code", req.CouponCode))
 
-    orderID, err := h.Storage.PlaceOrder(req.UserEmail, req.Items)
+    orderID, err := h.Storage.PlaceOrder(id.Email, req.OrderDraft)
     if err != nil {
-        h.log.Error("failed to place order", slog.Any("error", err))
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        status := placeOrderErrorStatus(err)
+        if status == http.StatusInternalServerError {
+            h.log.Error("failed to place order", slog.Any("error", err))
+        }
+        http.Error(w, err.Error(), status)
         return
     }
 
-    w.WriteHeader(http.StatusCreated)
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(map[string]int{"order_id": orderID})
+    payOrder(h.log, h.Payments, w, r, orderID, req.PaymentToken)
+}
+
+// payOrder проводит оплату только что созданного заказа и отвечает кодом
+// по её исходу: 201 — оплачен, 202 — ждёт подтверждения шлюза,
+// 402 — отказ, 504 — шлюз не ответил. Общий для PlaceOrder и оформления корзины.
+func payOrder(log *slog.Logger, payments Payments, w http.ResponseWriter, r *http.Request, orderID int, token string) {
+	pay, err := payments.Pay(r.Context(), orderID, token)
+	status := http.StatusCreated
+	switch {
+	case errors.Is(err, payment.ErrDeclined):
+		status = http.StatusPaymentRequired
+	case errors.Is(err, payment.ErrTimeout):
+		status = http.StatusGatewayTimeout
+	case err != nil:
+		log.Error("failed to process payment", slog.Int("order_id", orderID), slog.Any("error", err))
+		http.Error(w, "payment error", http.StatusBadGateway)
+		return
+	case pay.Status == models.PaymentPending:
+		status = http.StatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"order_id": orderID, "payment_status": pay.Status})
+}
+
+// placeOrderErrorStatus отделяет ошибки в составе заказа от ошибок базы.
+func placeOrderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrOutOfStock):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrVariantRequired),
+		errors.Is(err, storage.ErrVariantNotFound),
+		errors.Is(err, storage.ErrProductNotFound),
+		errors.Is(err, storage.ErrEmptyOrder),
+		errors.Is(err, storage.ErrCouponNotFound),
+		errors.Is(err, storage.ErrCouponNotApplicable),
+		errors.Is(err, storage.ErrTaxRegionNotFound),
+		errors.Is(err, storage.ErrAddressNotFound),
+		errors.Is(err, storage.ErrShippingMethodRequired),
+		errors.Is(err, storage.ErrShippingMethodNotFound),
+		errors.Is(err, storage.ErrShippingUnavailable),
+		errors.Is(err, storage.ErrPetNotFound):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
 }
 
 func (h *OrdersHandler) GetUserOrderHistory(w http.ResponseWriter, r *http.Request) {
@@ -132,10 +191,8 @@ func (h *OrdersHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, err := h.Storage.GetOrderByID(id)
-	if err != nil {
-		h.log.Error("failed to get order", slog.Any("error", err))
-		http.Error(w, "order not found", http.StatusNotFound)
+	order, ok := h.ownOrder(w, r, id)
+	if !ok {
 		return
 	}
 
@@ -146,12 +203,48 @@ func (h *OrdersHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	history, err := h.Storage.GetOrderStatusHistory(order.ID)
+	if err != nil {
+		h.log.Error("failed to get order status history", slog.Any("error", err))
+		http.Error(w, "error loading status history", http.StatusInternalServerError)
+		return
+	}
+
+	discounts, err := h.Storage.GetOrderDiscounts(order.ID)
+	if err != nil {
+		h.log.Error("failed to get order discounts", slog.Any("error", err))
+		http.Error(w, "error loading discounts", http.StatusInternalServerError)
+		return
+	}
+
+	taxLines, err := h.Storage.GetOrderTaxLines(order.ID)
+	if err != nil {
+		h.log.Error("failed to get order tax lines", slog.Any("error", err))
+		http.Error(w, "error loading tax lines", http.StatusInternalServerError)
+		return
+	}
+
+	shipment, err := h.Storage.GetOrderShipment(order.ID)
+	if err != nil {
+		h.log.Error("failed to get order shipment", slog.Any("error", err))
+		http.Error(w, "error loading shipment", http.StatusInternalServerError)
+		return
+	}
+
 	resp := struct {
-		Order  models.Order        `json:"order"`
-		Items  []models.OrderItem  `json:"items"`
+		Order         models.Order               `json:"order"`
+		Items         []models.OrderItem         `json:"items"`
+		Discounts     []models.OrderDiscount     `json:"discounts"`
+		TaxLines      []models.OrderTaxLine      `json:"tax_lines"`
+		Shipment      *models.OrderShipment      `json:"shipment"` // null — заказ без доставки
+		StatusHistory []models.OrderStatusChange `json:"status_history"`
 	}{
-		Order: order,
-		Items: items,
+		Order:         order,
+		Items:         items,
+		Discounts:     discounts,
+		TaxLines:      taxLines,
+		Shipment:      shipment,
+		StatusHistory: history,
 	}
 
 	render.JSON(w, r, resp)
@@ -173,3 +266,119 @@ func (h *OrdersHandler) GetOrdersByUserEmail(w http.ResponseWriter, r *http.Requ
 
 	render.JSON(w, r, orders)
 }
+
+type transitionRequest struct {
+	Status  models.OrderStatus `json:"status"`
+	Comment string             `json:"comment"`
+}
+
+// TransitionOrder переводит заказ в новый статус. Допустимые переходы
+// определены в models.OrderStatus, запрещённый переход даёт 409.
+func (h *OrdersHandler) TransitionOrder(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var req transitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !req.Status.Valid() {
+		http.Error(w, "unknown status", http.StatusBadRequest)
+		return
+	}
+
+	// Отмена и возврат через общий эндпоинт тоже должны вернуть товар на склад.
+	switch req.Status {
+	case models.OrderCancelled:
+		_, err = h.Storage.CancelOrder(orderID, caller.UserID)
+	case models.OrderRefunded:
+		_, err = h.Storage.RefundOrder(orderID, caller.UserID, req.Comment)
+	default:
+		err = h.Storage.TransitionOrder(orderID, req.Status, caller.UserID, req.Comment)
+	}
+	switch {
+	case errors.Is(err, storage.ErrOrderNotFound):
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	case errors.Is(err, models.ErrInvalidTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.log.Error("failed to transition order", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Status == models.OrderCancelled || req.Status == models.OrderRefunded {
+		if !h.releasePayment(w, r, orderID) {
+			return
+		}
+	}
+
+	render.JSON(w, r, map[string]string{"status": string(req.Status)})
+}
+
+// CancelOrder отменяет заказ покупателя. Повторный вызов безопасен и
+// возвращает тот же ответ; отгруженный заказ отменить нельзя (409).
+func (h *OrdersHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.ownOrder(w, r, orderID); !ok {
+		return
+	}
+
+	alreadyCancelled, err := h.Storage.CancelOrder(orderID, caller.UserID)
+	switch {
+	case errors.Is(err, storage.ErrOrderNotFound):
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	case errors.Is(err, models.ErrInvalidTransition):
+		http.Error(w, "order can no longer be cancelled", http.StatusConflict)
+		return
+	case err != nil:
+		h.log.Error("failed to cancel order", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if alreadyCancelled {
+		h.log.Info("order already cancelled", slog.Int("order_id", orderID))
+	}
+	// Повторная отмена повторяет и возврат, если прошлый не удался.
+	if !h.releasePayment(w, r, orderID) {
+		return
+	}
+
+	render.JSON(w, r, map[string]any{"order_id": orderID, "status": models.OrderCancelled})
+}
+
+// releasePayment возвращает деньги по отменённому заказу. Заказ к этому
+// моменту уже отменён; если шлюз не вернул деньги, отвечает 502 — возврат
+// повторит reservation.Sweeper или повторный запрос клиента.
+func (h *OrdersHandler) releasePayment(w http.ResponseWriter, r *http.Request, orderID int) bool {
+	if err := h.Payments.Release(r.Context(), orderID); err != nil {
+		h.log.Error("failed to release payment", slog.Int("order_id", orderID), slog.Any("error", err))
+		http.Error(w, "order status changed, but payment release failed and will be retried", http.StatusBadGateway)
+		return false
+	}
+	return true
+}
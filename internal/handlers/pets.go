@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Pets interface {
+	GetPets(email string) ([]models.Pet, error)
+	GetPetByID(id int) (models.Pet, error)
+	CreatePet(email string, pet models.Pet) (models.Pet, error)
+	UpdatePet(email string, pet models.Pet) (models.Pet, error)
+	DeletePet(email string, id int) error
+}
+
+// petErrorStatus подбирает HTTP-статус для ошибок storage по питомцам.
+func petErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrUnknownSpecies):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrUserNotFound), errors.Is(err, storage.ErrPetNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// decodePet читает профиль питомца из тела запроса и проверяет его.
+func decodePet(w http.ResponseWriter, r *http.Request) (models.Pet, bool) {
+	var pet models.Pet
+	if err := render.DecodeJSON(r.Body, &pet); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return pet, false
+	}
+
+	pet.Normalize()
+	if err := pet.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return pet, false
+	}
+
+	return pet, true
+}
+
+// GetPets возвращает питомцев пользователя {email}.
+func GetPets(log *slog.Logger, pets Pets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pets.GetPets"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		list, err := pets.GetPets(chi.URLParam(r, "email"))
+		if err != nil {
+			status := petErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to get pets", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.JSON(w, r, list)
+	}
+}
+
+func CreatePet(log *slog.Logger, pets Pets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pets.CreatePet"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		pet, ok := decodePet(w, r)
+		if !ok {
+			return
+		}
+
+		pet, err := pets.CreatePet(chi.URLParam(r, "email"), pet)
+		if err != nil {
+			status := petErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to create pet", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, pet)
+	}
+}
+
+func UpdatePet(log *slog.Logger, pets Pets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pets.UpdatePet"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid pet ID", http.StatusBadRequest)
+			return
+		}
+
+		pet, ok := decodePet(w, r)
+		if !ok {
+			return
+		}
+		pet.ID = id
+
+		pet, err = pets.UpdatePet(chi.URLParam(r, "email"), pet)
+		if err != nil {
+			status := petErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to update pet", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, pet)
+	}
+}
+
+func DeletePet(log *slog.Logger, pets Pets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pets.DeletePet"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid pet ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := pets.DeletePet(chi.URLParam(r, "email"), id); err != nil {
+			status := petErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to delete pet", slog.Any("error", err))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Pet deleted successfully"})
+	}
+}
+
+// applyPetFilter убирает из запроса каталога товары, не подходящие питомцу
+// petID по виду или весу. Товары без указанных видов и весов остаются.
+// Профиль доступен владельцу и персоналу, чужой питомец выглядит так же,
+// как несуществующий.
+func applyPetFilter(log *slog.Logger, pets Pets, w http.ResponseWriter, r *http.Request, petID string, query *models.ProductQuery) bool {
+	id, err := strconv.Atoi(petID)
+	if err != nil {
+		http.Error(w, "invalid pet_id", http.StatusBadRequest)
+		return false
+	}
+
+	caller, ok := auth.IdentityFromContext(r.Context())
+	if !ok {
+		http.Error(w, "pet_id requires authentication", http.StatusUnauthorized)
+		return false
+	}
+
+	pet, err := pets.GetPetByID(id)
+	if err != nil {
+		status := petErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Error("failed to get pet", slog.Any("error", err))
+		}
+		http.Error(w, http.StatusText(status), status)
+		return false
+	}
+	if !auth.CanAccessPet(caller, pet.UserID) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return false
+	}
+
+	if query.Species != "" && query.Species != pet.Species {
+		http.Error(w, "species does not match pet", http.StatusBadRequest)
+		return false
+	}
+	query.PetSpecies = pet.Species
+	query.PetWeightGrams = pet.WeightGrams
+
+	return true
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/productimport"
+	"go-pet-shop/models"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+// Максимальный размер файла импорта.
+const maxImportSize = 32 << 20
+
+// importFormat берёт формат из ?format, иначе из Content-Type.
+func importFormat(r *http.Request) models.ImportFormat {
+	if v := r.URL.Query().Get("format"); v != "" {
+		return models.ImportFormat(v)
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return models.ImportCSV
+	case "application/x-ndjson", "application/ndjson":
+		return models.ImportNDJSON
+	}
+	return ""
+}
+
+// ImportProducts загружает каталог из тела запроса в формате CSV или NDJSON,
+// см. пакет productimport. С ?dry_run=true только возвращает отчёт. Если
+// есть отклонённые строки, ничего не сохраняется и ответ — 422 с отчётом.
+func ImportProducts(log *slog.Logger, store productimport.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.product_import.ImportProducts"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		format := importFormat(r)
+		if !format.Valid() {
+			http.Error(w, "format must be one of csv, ndjson", http.StatusBadRequest)
+			return
+		}
+
+		dryRun := false
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "invalid dry_run", http.StatusBadRequest)
+				return
+			}
+		}
+
+		actor := actorID(r)
+		body := http.MaxBytesReader(w, r.Body, maxImportSize)
+		report, err := productimport.Import(store, body, format, dryRun, &actor)
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			http.Error(w, "file is too large", http.StatusRequestEntityTooLarge)
+			return
+		case errors.Is(err, productimport.ErrInvalidFile):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			log.Error("failed to import products", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("products imported",
+			slog.Bool("dry_run", dryRun), slog.Bool("applied", report.Applied),
+			slog.Int("created", report.Created), slog.Int("updated", report.Updated), slog.Int("rejected", report.Rejected))
+
+		if report.Rejected > 0 && !dryRun {
+			render.Status(r, http.StatusUnprocessableEntity)
+		}
+		render.JSON(w, r, report)
+	}
+}
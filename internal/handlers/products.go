@@ -29,10 +29,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
 	"go-pet-shop/models"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -40,15 +44,20 @@ import (
 )
 
 type Products interface {
-	GetAllProducts() ([]models.Product, error)
-	CreateProduct(product models.Product) error
+	GetAllProducts(query models.ProductQuery) (models.ProductPage, error)
+	SearchProducts(query models.ProductSearchQuery) (models.ProductSearchPage, error)
+	CreateProduct(product models.Product, actorID int) error
 	DeleteProduct(id int) error
-	UpdateProduct(product models.Product) error
+	UpdateProduct(product models.Product, actorID int) error
 	GetProductByID(id int) (models.Product, error)
-	GetPopularProducts() ([]models.PopularProduct, error)
+	GetProductVariants(productID int) ([]models.ProductVariant, error)
+	GetPopularProducts(query models.PopularQuery) ([]models.PopularProduct, error)
+	GetPopularProductsByCategory(perCategory int) ([]models.CategoryPopularProducts, error)
 }
 
-func GetAllProducts(log *slog.Logger, products Products) http.HandlerFunc {
+// GetAllProducts возвращает страницу каталога. ?pet_id подбирает товары
+// под вид и вес питомца вошедшего покупателя.
+func GetAllProducts(log *slog.Logger, products Products, pets Pets) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.products.GetAllProducts"
 
@@ -57,8 +66,23 @@ func GetAllProducts(log *slog.Logger, products Products) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		products, err := products.GetAllProducts()
+		query, err := parseProductQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
+		if v := r.URL.Query().Get("pet_id"); v != "" {
+			if !applyPetFilter(log, pets, w, r, v, &query) {
+				return
+			}
+		}
+
+		page, err := products.GetAllProducts(query)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Error("failed to get products", slog.Any("error", err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -67,7 +91,121 @@ func GetAllProducts(log *slog.Logger, products Products) http.HandlerFunc {
 
 		log.Info("Retrieved products successfully", slog.String("url", r.URL.String()))
 
-		render.JSON(w, r, products)
+		render.JSON(w, r, page)
+	}
+}
+
+const (
+	defaultProductLimit = 20
+	maxProductLimit     = 100
+)
+
+// parseProductQuery читает параметры GET /products:
+// limit, cursor, min_price, max_price, in_stock, category, species и sort.
+// pet_id разбирает GetAllProducts: для него нужен вошедший пользователь.
+func parseProductQuery(r *http.Request) (models.ProductQuery, error) {
+	params := r.URL.Query()
+	query := models.ProductQuery{
+		Limit:  defaultProductLimit,
+		Cursor: params.Get("cursor"),
+		Sort:   params.Get("sort"),
+	}
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxProductLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxProductLimit)
+		}
+		query.Limit = limit
+	}
+
+	for name, dst := range map[string]**models.Money{"min_price": &query.MinPrice, "max_price": &query.MaxPrice} {
+		v := params.Get(name)
+		if v == "" {
+			continue
+		}
+		price, err := models.ParseMoney(v)
+		if err != nil || price < 0 {
+			return query, fmt.Errorf("invalid %s", name)
+		}
+		*dst = &price
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return query, errors.New("min_price must not exceed max_price")
+	}
+
+	if v := params.Get("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			return query, errors.New("invalid in_stock")
+		}
+		query.InStock = inStock
+	}
+
+	if v := params.Get("category"); v != "" {
+		categoryID, err := strconv.Atoi(v)
+		if err != nil {
+			return query, errors.New("invalid category")
+		}
+		query.CategoryID = &categoryID
+	}
+
+	query.Species = models.Species(params.Get("species"))
+
+	switch query.Sort {
+	case "", models.SortPriceAsc, models.SortPriceDesc, models.SortNameAsc, models.SortNameDesc, models.SortNewest, models.SortRating:
+	default:
+		return query, errors.New("sort must be one of price_asc, price_desc, name_asc, name_desc, newest, rating")
+	}
+
+	return query, nil
+}
+
+func SearchProducts(log *slog.Logger, products Products) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.products.SearchProducts"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		params := r.URL.Query()
+		query := models.ProductSearchQuery{
+			Text:  strings.TrimSpace(params.Get("q")),
+			Limit: defaultProductLimit,
+		}
+		if query.Text == "" {
+			http.Error(w, "q is required", http.StatusBadRequest)
+			return
+		}
+
+		if v := params.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxProductLimit {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxProductLimit), http.StatusBadRequest)
+				return
+			}
+			query.Limit = limit
+		}
+
+		if v := params.Get("offset"); v != "" {
+			offset, err := strconv.Atoi(v)
+			if err != nil || offset < 0 {
+				http.Error(w, "invalid offset", http.StatusBadRequest)
+				return
+			}
+			query.Offset = offset
+		}
+
+		page, err := products.SearchProducts(query)
+		if err != nil {
+			log.Error("failed to search products", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, page)
 	}
 }
 
@@ -89,9 +227,22 @@ func CreateProduct(log *slog.Logger, products Products) http.HandlerFunc {
 			return
 		}
 
-		if err := products.CreateProduct(product); err != nil {
+		if !normalizeTaxClass(&product) {
+			http.Error(w, "TaxClass must be one of standard, reduced, exempt", http.StatusBadRequest)
+			return
+		}
+		if product.WeightGrams < 0 {
+			http.Error(w, "WeightGrams must not be negative", http.StatusBadRequest)
+			return
+		}
+		if !validPetWeightRange(product) {
+			http.Error(w, "PetMinWeightGrams and PetMaxWeightGrams must be positive and ordered", http.StatusBadRequest)
+			return
+		}
+
+		if err := products.CreateProduct(product, actorID(r)); err != nil {
 			log.Error("failed to create product", slog.Any("error", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), productErrorStatus(err))
 			return
 		}
 
@@ -101,10 +252,109 @@ func CreateProduct(log *slog.Logger, products Products) http.HandlerFunc {
 	}
 }
 
+// normalizeTaxClass подставляет standard вместо пустого налогового класса
+// и сообщает, допустим ли класс.
+func normalizeTaxClass(p *models.Product) bool {
+	if p.TaxClass == "" {
+		p.TaxClass = models.TaxStandard
+	}
+	return p.TaxClass.Valid()
+}
+
+// validPetWeightRange проверяет диапазон веса питомцев, которым подходит товар.
+func validPetWeightRange(p models.Product) bool {
+	if p.PetMinWeightGrams != nil && *p.PetMinWeightGrams <= 0 || p.PetMaxWeightGrams != nil && *p.PetMaxWeightGrams <= 0 {
+		return false
+	}
+	return p.PetMinWeightGrams == nil || p.PetMaxWeightGrams == nil || *p.PetMinWeightGrams <= *p.PetMaxWeightGrams
+}
+
+const (
+	popularPerCategory  = 10
+	defaultPopularLimit = 10
+	maxPopularLimit     = 100
+	defaultPopularDays  = 30
+)
+
+// parsePopularQuery читает limit, category, metric (units или revenue)
+// и период from, to, tz. Без from и to отчёт строится за всё время.
+func parsePopularQuery(r *http.Request) (models.PopularQuery, error) {
+	params := r.URL.Query()
+	query := models.PopularQuery{
+		Limit:  defaultPopularLimit,
+		Metric: models.PopularityMetric(params.Get("metric")),
+	}
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPopularLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxPopularLimit)
+		}
+		query.Limit = limit
+	}
+
+	if v := params.Get("category"); v != "" {
+		categoryID, err := strconv.Atoi(v)
+		if err != nil {
+			return query, errors.New("invalid category")
+		}
+		query.CategoryID = &categoryID
+	}
+
+	if query.Metric == "" {
+		query.Metric = models.PopularityUnits
+	}
+	if !query.Metric.Valid() {
+		return query, errors.New("metric must be one of units, revenue")
+	}
+
+	if params.Get("from") != "" || params.Get("to") != "" {
+		var err error
+		query.From, query.To, query.Timezone, err = parseDateRange(params, defaultPopularDays)
+		if err != nil {
+			return query, err
+		}
+	}
+
+	return query, nil
+}
+
+// productErrorStatus отличает ошибки в данных товара от ошибок базы.
+func productErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrUnknownSpecies) || errors.Is(err, storage.ErrCategoryNotFound) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, storage.ErrProductNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, storage.ErrSKUExists) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 func GetPopularProducts(log *slog.Logger, products Products) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		popularProducts, err := products.GetPopularProducts()
+		if r.URL.Query().Get("by") == "category" {
+			groups, err := products.GetPopularProductsByCategory(popularPerCategory)
+			if err != nil {
+				log.Error("failed to get popular products by category", slog.String("error", err.Error()))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			render.JSON(w, r, groups)
+			return
+		}
+
+		query, err := parsePopularQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		popularProducts, err := products.GetPopularProducts(query)
 		if err != nil {
 			log.Error("failed to get popular products", slog.String("error", err.Error()))
 			http.Error(w, "internal error", http.StatusInternalServerError)
@@ -166,9 +416,22 @@ func UpdateProduct(log *slog.Logger, products Products) http.HandlerFunc {
 			return
 		}
 
-		if err := products.UpdateProduct(product); err != nil {
+		if !normalizeTaxClass(&product) {
+			http.Error(w, "TaxClass must be one of standard, reduced, exempt", http.StatusBadRequest)
+			return
+		}
+		if product.WeightGrams < 0 {
+			http.Error(w, "WeightGrams must not be negative", http.StatusBadRequest)
+			return
+		}
+		if !validPetWeightRange(product) {
+			http.Error(w, "PetMinWeightGrams and PetMaxWeightGrams must be positive and ordered", http.StatusBadRequest)
+			return
+		}
+
+		if err := products.UpdateProduct(product, actorID(r)); err != nil {
 			log.Error("failed to update product", slog.Any("error", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), productErrorStatus(err))
 			return
 		}
 
@@ -206,6 +469,13 @@ func GetProductByID(log *slog.Logger, products Products) http.HandlerFunc {
 			return
 		}
 
-		render.JSON(w, r, product)
+		variants, err := products.GetProductVariants(id)
+		if err != nil {
+			log.Error("failed to get product variants", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, models.NewProductDetails(product, variants))
 	}
 }
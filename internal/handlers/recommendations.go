@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Recommendations interface {
+	GetRecommendations(productID int, q models.RecommendationQuery) ([]models.Recommendation, error)
+	GetRecommendationsForProducts(seed, exclude []int, q models.RecommendationQuery) ([]models.Recommendation, error)
+}
+
+type OrderHistory interface {
+	GetUserOrderHistory(email string) ([]models.OrderDetail, error)
+}
+
+const (
+	defaultRecommendationLimit = 10
+	maxRecommendationLimit     = 50
+)
+
+// parseRecommendationQuery читает limit и score (lift или confidence).
+func parseRecommendationQuery(r *http.Request) (models.RecommendationQuery, error) {
+	params := r.URL.Query()
+	query := models.RecommendationQuery{Limit: defaultRecommendationLimit, Score: models.ScoreLift}
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxRecommendationLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxRecommendationLimit)
+		}
+		query.Limit = limit
+	}
+
+	if v := params.Get("score"); v != "" {
+		query.Score = models.RecommendationScore(v)
+		if !query.Score.Valid() {
+			return query, errors.New("score must be one of lift, confidence")
+		}
+	}
+
+	return query, nil
+}
+
+// GetProductRecommendations возвращает товары, которые часто покупают
+// вместе с товаром {id}.
+func GetProductRecommendations(log *slog.Logger, recs Recommendations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.recommendations.GetProductRecommendations"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid product ID", http.StatusBadRequest)
+			return
+		}
+
+		query, err := parseRecommendationQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		list, err := recs.GetRecommendations(productID, query)
+		if err != nil {
+			log.Error("failed to get recommendations", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, list)
+	}
+}
+
+// GetUserRecommendations подбирает товары пользователю {email} по его
+// истории заказов. Купленное за последние recentWindow не предлагается,
+// отменённые и возвращённые заказы не учитываются.
+func GetUserRecommendations(log *slog.Logger, orders OrderHistory, recs Recommendations, recentWindow time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.recommendations.GetUserRecommendations"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query, err := parseRecommendationQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		history, err := orders.GetUserOrderHistory(chi.URLParam(r, "email"))
+		if err != nil {
+			log.Error("failed to get order history", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		seed, recent := purchasedProducts(history, time.Now().Add(-recentWindow))
+
+		list, err := recs.GetRecommendationsForProducts(seed, recent, query)
+		if err != nil {
+			log.Error("failed to get recommendations", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, list)
+	}
+}
+
+// purchasedProducts возвращает все купленные товары из истории и те из них,
+// что куплены после since.
+func purchasedProducts(history []models.OrderDetail, since time.Time) (all, recent []int) {
+	seen := map[int]bool{}
+	seenRecent := map[int]bool{}
+	for _, od := range history {
+		switch models.OrderStatus(od.Status) {
+		case models.OrderCancelled, models.OrderRefunded:
+			continue
+		}
+
+		if !seen[od.ProductID] {
+			seen[od.ProductID] = true
+			all = append(all, od.ProductID)
+		}
+		if od.CreatedAt.After(since) && !seenRecent[od.ProductID] {
+			seenRecent[od.ProductID] = true
+			recent = append(recent, od.ProductID)
+		}
+	}
+	return all, recent
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Reviews interface {
+	GetReviews(q models.ReviewQuery) ([]models.Review, error)
+	GetReviewByID(id int) (models.Review, error)
+	CreateReview(review models.Review) (models.Review, error)
+	UpdateReview(review models.Review) (models.Review, error)
+	ModerateReview(id int, status models.ReviewStatus, comment string) (models.Review, error)
+	DeleteReview(id int) error
+}
+
+const (
+	defaultReviewLimit = 20
+	maxReviewLimit     = 100
+)
+
+// reviewErrorStatus подбирает HTTP-статус для ошибок storage по отзывам.
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidReview):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrReviewNotFound), errors.Is(err, storage.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrReviewExists):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// parseReviewQuery читает limit, offset и verified.
+func parseReviewQuery(r *http.Request) (models.ReviewQuery, error) {
+	params := r.URL.Query()
+	query := models.ReviewQuery{Limit: defaultReviewLimit}
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxReviewLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxReviewLimit)
+		}
+		query.Limit = limit
+	}
+
+	if v := params.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return query, errors.New("invalid offset")
+		}
+		query.Offset = offset
+	}
+
+	if v := params.Get("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			return query, errors.New("invalid verified")
+		}
+		query.VerifiedOnly = verified
+	}
+
+	return query, nil
+}
+
+// decodeReview читает оценку, заголовок и текст отзыва из тела запроса.
+func decodeReview(w http.ResponseWriter, r *http.Request) (models.Review, bool) {
+	var review models.Review
+	if err := render.DecodeJSON(r.Body, &review); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return review, false
+	}
+
+	review.Normalize()
+	if err := review.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return review, false
+	}
+
+	return review, true
+}
+
+// GetProductReviews возвращает одобренные отзывы о товаре, новые первыми.
+// ?verified=true оставляет только отзывы подтверждённых покупателей.
+func GetProductReviews(log *slog.Logger, reviews Reviews) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.reviews.GetProductReviews"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid product ID", http.StatusBadRequest)
+			return
+		}
+
+		query, err := parseReviewQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.ProductID = &productID
+
+		list, err := reviews.GetReviews(query)
+		if err != nil {
+			log.Error("failed to get reviews", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, list)
+	}
+}
+
+// GetReviewsForModeration возвращает очередь модерации: отзывы в статусе
+// ?status, по умолчанию pending.
+func GetReviewsForModeration(log *slog.Logger, reviews Reviews) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.reviews.GetReviewsForModeration"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query, err := parseReviewQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query.Status = models.ReviewStatus(r.URL.Query().Get("status"))
+		if query.Status == "" {
+			query.Status = models.ReviewPending
+		}
+		if !query.Status.Valid() {
+			http.Error(w, "status must be one of pending, approved, rejected", http.StatusBadRequest)
+			return
+		}
+
+		list, err := reviews.GetReviews(query)
+		if err != nil {
+			log.Error("failed to get reviews", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, list)
+	}
+}
+
+// CreateReview оставляет отзыв вошедшего пользователя о товаре.
+// Отзыв публикуется после модерации.
+func CreateReview(log *slog.Logger, reviews Reviews) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.reviews.CreateReview"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid product ID", http.StatusBadRequest)
+			return
+		}
+
+		review, ok := decodeReview(w, r)
+		if !ok {
+			return
+		}
+		review.ProductID = productID
+		review.UserID = actorID(r)
+
+		review, err = reviews.CreateReview(review)
+		if err != nil {
+			status := reviewErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to create review", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, review)
+	}
+}
+
+// UpdateReview меняет отзыв. Менять отзыв может только автор, после правки
+// он снова уходит на модерацию.
+func UpdateReview(log *slog.Logger, reviews Reviews) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.reviews.UpdateReview"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid review ID", http.StatusBadRequest)
+			return
+		}
+
+		existing, ok := ownReview(log, reviews, w, r, id, auth.CanEditReview)
+		if !ok {
+			return
+		}
+
+		review, ok := decodeReview(w, r)
+		if !ok {
+			return
+		}
+		review.ID = existing.ID
+
+		review, err = reviews.UpdateReview(review)
+		if err != nil {
+			status := reviewErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to update review", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, review)
+	}
+}
+
+func DeleteReview(log *slog.Logger, reviews Reviews) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.reviews.DeleteReview"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid review ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := ownReview(log, reviews, w, r, id, auth.CanDeleteReview); !ok {
+			return
+		}
+
+		if err := reviews.DeleteReview(id); err != nil {
+			status := reviewErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to delete review", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Review deleted successfully"})
+	}
+}
+
+type moderationRequest struct {
+	Status  models.ReviewStatus `json:"status"`
+	Comment string              `json:"comment"`
+}
+
+// ModerateReview одобряет или отклоняет отзыв. Рейтинг товара
+// пересчитывается сразу.
+func ModerateReview(log *slog.Logger, reviews Reviews) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.reviews.ModerateReview"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid review ID", http.StatusBadRequest)
+			return
+		}
+
+		var req moderationRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if !req.Status.Valid() {
+			http.Error(w, "status must be one of pending, approved, rejected", http.StatusBadRequest)
+			return
+		}
+
+		review, err := reviews.ModerateReview(id, req.Status, req.Comment)
+		if err != nil {
+			status := reviewErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to moderate review", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		log.Info("review moderated", slog.Int("review_id", id), slog.String("status", string(req.Status)))
+
+		render.JSON(w, r, review)
+	}
+}
+
+// ownReview загружает отзыв и проверяет право вызывающего на действие.
+// Чужой отзыв выглядит так же, как несуществующий.
+func ownReview(log *slog.Logger, reviews Reviews, w http.ResponseWriter, r *http.Request, id int, allowed func(auth.Identity, int) bool) (models.Review, bool) {
+	review, err := reviews.GetReviewByID(id)
+	if err != nil {
+		status := reviewErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Error("failed to get review", slog.Any("error", err))
+		}
+		http.Error(w, http.StatusText(status), status)
+		return review, false
+	}
+
+	caller, _ := auth.IdentityFromContext(r.Context())
+	if !allowed(caller, review.UserID) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return review, false
+	}
+
+	return review, true
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Taxes interface {
+	GetTaxRegions() ([]models.TaxRegion, error)
+	SaveTaxRegion(region models.TaxRegion) error
+}
+
+// GetTaxRegions возвращает налоговые регионы, которые можно указать
+// в tax_region при оформлении заказа, и их ставки.
+func GetTaxRegions(log *slog.Logger, taxes Taxes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.tax.GetTaxRegions"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		regions, err := taxes.GetTaxRegions()
+		if err != nil {
+			log.Error("failed to get tax regions", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, regions)
+	}
+}
+
+// SaveTaxRegion создаёт регион или заменяет его название, режим цен и ставки.
+func SaveTaxRegion(log *slog.Logger, taxes Taxes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.tax.SaveTaxRegion"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var region models.TaxRegion
+		if err := render.DecodeJSON(r.Body, &region); err != nil {
+			log.Error("failed to decode request body", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		region.Code = strings.ToUpper(chi.URLParam(r, "code"))
+
+		if err := region.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := taxes.SaveTaxRegion(region); err != nil {
+			log.Error("failed to save tax region", slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, region)
+	}
+}
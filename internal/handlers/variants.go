@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Variants interface {
+	CreateProductVariant(variant models.ProductVariant, actorID int) (int, error)
+	UpdateProductVariant(variant models.ProductVariant, actorID int) error
+	DeleteProductVariant(productID, variantID int) error
+}
+
+func variantErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrVariantNotFound), errors.Is(err, storage.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrSKUExists):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeVariant читает вариант из тела запроса и id товара (и варианта) из URL.
+func decodeVariant(r *http.Request, withID bool) (models.ProductVariant, error) {
+	var v models.ProductVariant
+	if err := render.DecodeJSON(r.Body, &v); err != nil {
+		return v, err
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return v, errors.New("invalid product ID")
+	}
+	v.ProductID = productID
+
+	if withID {
+		variantID, err := strconv.Atoi(chi.URLParam(r, "variantID"))
+		if err != nil {
+			return v, errors.New("invalid variant ID")
+		}
+		v.ID = variantID
+	}
+
+	if v.SKU == "" {
+		return v, errors.New("sku is required")
+	}
+	if v.Price < 0 || v.Stock < 0 {
+		return v, errors.New("price and stock must not be negative")
+	}
+
+	return v, nil
+}
+
+func CreateProductVariant(log *slog.Logger, variants Variants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.variants.CreateProductVariant"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		variant, err := decodeVariant(r, false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id, err := variants.CreateProductVariant(variant, actorID(r))
+		if err != nil {
+			status := variantErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to create variant", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, map[string]int{"id": id})
+	}
+}
+
+func UpdateProductVariant(log *slog.Logger, variants Variants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.variants.UpdateProductVariant"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		variant, err := decodeVariant(r, true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := variants.UpdateProductVariant(variant, actorID(r)); err != nil {
+			status := variantErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to update variant", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Variant updated successfully"})
+	}
+}
+
+func DeleteProductVariant(log *slog.Logger, variants Variants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.variants.DeleteProductVariant"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "invalid product ID", http.StatusBadRequest)
+			return
+		}
+		variantID, err := strconv.Atoi(chi.URLParam(r, "variantID"))
+		if err != nil {
+			http.Error(w, "invalid variant ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := variants.DeleteProductVariant(productID, variantID); err != nil {
+			status := variantErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Error("failed to delete variant", slog.Any("error", err))
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "Variant deleted successfully"})
+	}
+}
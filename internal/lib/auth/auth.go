@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go-pet-shop/models"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+// Identity описывает аутентифицированного пользователя, полученного из токена.
+type Identity struct {
+	UserID int         `json:"user_id"`
+	Email  string      `json:"email"`
+	Role   models.Role `json:"role"`
+}
+
+func (id Identity) HasRole(roles ...models.Role) bool {
+	for _, role := range roles {
+		if id.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{}
+
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(Identity)
+	return id, ok
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"go-pet-shop/models"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type claims struct {
+	Email string      `json:"email"`
+	Role  models.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager выпускает и проверяет JWT, подписанные ключом Ed25519.
+type TokenManager struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	issuer     string
+	ttl        time.Duration
+}
+
+func NewTokenManager(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey, issuer string, ttl time.Duration) *TokenManager {
+	return &TokenManager{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		issuer:     issuer,
+		ttl:        ttl,
+	}
+}
+
+func (m *TokenManager) Issue(id Identity) (string, error) {
+	const fn = "lib.auth.Issue"
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims{
+		Email: id.Email,
+		Role:  id.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(id.UserID),
+			Issuer:    m.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+		},
+	})
+
+	signed, err := token.SignedString(m.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return signed, nil
+}
+
+func (m *TokenManager) Parse(tokenString string) (Identity, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(tokenString, &c,
+		func(*jwt.Token) (any, error) { return m.publicKey, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	userID, err := strconv.Atoi(c.Subject)
+	if err != nil || c.Email == "" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	// Токены, выпущенные до появления ролей, получают минимальные права.
+	if c.Role == "" {
+		c.Role = models.RoleCustomer
+	}
+	if !c.Role.Valid() {
+		return Identity{}, ErrInvalidToken
+	}
+
+	return Identity{UserID: userID, Email: c.Email, Role: c.Role}, nil
+}
+
+// LoadKeys читает пару ключей Ed25519 из PEM-файлов (PKCS#8 и PKIX).
+// Сгенерировать их локально можно командой `task keys`.
+func LoadKeys(privateKeyPath, publicKeyPath string) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	const fn = "lib.auth.LoadKeys"
+
+	privPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	pubPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	block, _ := pem.Decode(privPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("%s: no PEM data in %s", fn, privateKeyPath)
+	}
+	privKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	priv, ok := privKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: %s is not an Ed25519 key", fn, privateKeyPath)
+	}
+
+	block, _ = pem.Decode(pubPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("%s: no PEM data in %s", fn, publicKeyPath)
+	}
+	pubKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	pub, ok := pubKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: %s is not an Ed25519 key", fn, publicKeyPath)
+	}
+
+	return priv, pub, nil
+}
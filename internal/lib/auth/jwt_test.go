@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"go-pet-shop/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "go-pet-shop-test"
+
+func newTestKeys(t *testing.T) (ed25519.PrivateKey, ed25519.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return priv, pub
+}
+
+func newTestTokenManager(t *testing.T, ttl time.Duration) *TokenManager {
+	t.Helper()
+
+	priv, pub := newTestKeys(t)
+	return NewTokenManager(priv, pub, testIssuer, ttl)
+}
+
+var testIdentity = Identity{UserID: 42, Email: "user@example.com", Role: models.RoleStaff}
+
+func TestTokenRoundTrip(t *testing.T) {
+	tokens := newTestTokenManager(t, time.Hour)
+
+	signed, err := tokens.Issue(testIdentity)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	got, err := tokens.Parse(signed)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got != testIdentity {
+		t.Errorf("Parse = %+v, want %+v", got, testIdentity)
+	}
+}
+
+func TestParseRejectsExpiredToken(t *testing.T) {
+	tokens := newTestTokenManager(t, -time.Minute)
+
+	signed, err := tokens.Issue(testIdentity)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	_, err = tokens.Parse(signed)
+	if !errors.Is(err, ErrInvalidToken) || !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("Parse error = %v, want expired ErrInvalidToken", err)
+	}
+}
+
+func TestParseRejectsWrongKey(t *testing.T) {
+	tokens := newTestTokenManager(t, time.Hour)
+	other := newTestTokenManager(t, time.Hour)
+	other.issuer = tokens.issuer
+
+	signed, err := other.Issue(testIdentity)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	_, err = tokens.Parse(signed)
+	if !errors.Is(err, ErrInvalidToken) || !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("Parse error = %v, want invalid signature", err)
+	}
+}
+
+func TestParseRejectsOtherIssuer(t *testing.T) {
+	priv, pub := newTestKeys(t)
+	tokens := NewTokenManager(priv, pub, testIssuer, time.Hour)
+	other := NewTokenManager(priv, pub, "someone-else", time.Hour)
+
+	signed, err := other.Issue(testIdentity)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	if _, err := tokens.Parse(signed); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Parse error = %v, want ErrInvalidToken", err)
+	}
+}
+
+// Подмена алгоритма: токен без подписи (alg=none) и токен, подписанный
+// HS256 с публичным ключом в качестве секрета, не должны приниматься.
+func TestParseRejectsAlgorithmSubstitution(t *testing.T) {
+	tokens := newTestTokenManager(t, time.Hour)
+
+	validClaims := claims{
+		Email: testIdentity.Email,
+		Role:  testIdentity.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(testIdentity.UserID),
+			Issuer:    testIssuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign none: %v", err)
+	}
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims).SignedString([]byte(tokens.publicKey))
+	if err != nil {
+		t.Fatalf("sign HS256: %v", err)
+	}
+
+	for name, signed := range map[string]string{"none": none, "HS256": hs256} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tokens.Parse(signed)
+			if !errors.Is(err, ErrInvalidToken) || !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+				t.Errorf("Parse error = %v, want invalid signature", err)
+			}
+		})
+	}
+}
+
+func TestParseRejectsMalformedToken(t *testing.T) {
+	tokens := newTestTokenManager(t, time.Hour)
+
+	for _, signed := range []string{"", "not-a-jwt", "a.b.c"} {
+		if _, err := tokens.Parse(signed); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidToken", signed, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// Middleware проверяет заголовок Authorization: Bearer <token> и кладёт
+// Identity в контекст запроса. Без валидного токена отвечает 401.
+func Middleware(log *slog.Logger, tokens *TokenManager) func(http.Handler) http.Handler {
+	return authenticate(log, tokens, true)
+}
+
+// OptionalMiddleware — вариант Middleware для маршрутов, доступных и без
+// входа (например, анонимная корзина). Запрос без заголовка Authorization
+// проходит без Identity, но неверный токен всё равно даёт 401.
+func OptionalMiddleware(log *slog.Logger, tokens *TokenManager) func(http.Handler) http.Handler {
+	return authenticate(log, tokens, false)
+}
+
+func authenticate(log *slog.Logger, tokens *TokenManager, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" && !required {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tokenString, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || tokenString == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				return
+			}
+
+			id, err := tokens.Parse(tokenString)
+			if err != nil {
+				log.Info("rejected token",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.Any("error", err),
+				)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serve пропускает запрос с заголовком Authorization через mw и возвращает
+// код ответа и Identity, которую увидел обработчик.
+func serve(mw func(http.Handler) http.Handler, authorization string) (int, *Identity) {
+	var seen *Identity
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := IdentityFromContext(r.Context()); ok {
+			seen = &id
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code, seen
+}
+
+func TestMiddleware(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tokens := newTestTokenManager(t, time.Hour)
+	signed, err := tokens.Issue(testIdentity)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		optional      bool
+		authorization string
+		wantStatus    int
+		wantIdentity  bool
+	}{
+		{name: "valid token", authorization: "Bearer " + signed, wantStatus: http.StatusOK, wantIdentity: true},
+		{name: "missing header", authorization: "", wantStatus: http.StatusUnauthorized},
+		{name: "other scheme", authorization: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized},
+		{name: "empty bearer", authorization: "Bearer ", wantStatus: http.StatusUnauthorized},
+		{name: "token without scheme", authorization: signed, wantStatus: http.StatusUnauthorized},
+		{name: "malformed token", authorization: "Bearer not-a-jwt", wantStatus: http.StatusUnauthorized},
+		{name: "optional without header", optional: true, authorization: "", wantStatus: http.StatusOK},
+		{name: "optional with valid token", optional: true, authorization: "Bearer " + signed, wantStatus: http.StatusOK, wantIdentity: true},
+		{name: "optional with malformed token", optional: true, authorization: "Bearer not-a-jwt", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := Middleware(log, tokens)
+			if tt.optional {
+				mw = OptionalMiddleware(log, tokens)
+			}
+
+			status, id := serve(mw, tt.authorization)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if got := id != nil; got != tt.wantIdentity {
+				t.Fatalf("identity present = %v, want %v", got, tt.wantIdentity)
+			}
+			if id != nil && *id != testIdentity {
+				t.Errorf("identity = %+v, want %+v", *id, testIdentity)
+			}
+		})
+	}
+}
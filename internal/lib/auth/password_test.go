@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPasswordRoundTrip(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if hash == "correct horse" {
+		t.Fatal("HashPassword returned the password itself")
+	}
+
+	if err := CheckPassword(hash, "correct horse"); err != nil {
+		t.Errorf("CheckPassword with the right password: %v", err)
+	}
+	if err := CheckPassword(hash, "battery staple"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("CheckPassword with a wrong password = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+// У пользователя без пароля (пустой hash) вход невозможен.
+func TestCheckPasswordEmptyHash(t *testing.T) {
+	if err := CheckPassword("", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("CheckPassword = %v, want ErrInvalidCredentials", err)
+	}
+}
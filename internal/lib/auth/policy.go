@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"go-pet-shop/models"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// Policy решает, может ли аутентифицированный пользователь выполнить запрос.
+type Policy func(r *http.Request, id Identity) bool
+
+// AnyRole разрешает запрос пользователям с одной из перечисленных ролей.
+func AnyRole(roles ...models.Role) Policy {
+	return func(_ *http.Request, id Identity) bool {
+		return id.HasRole(roles...)
+	}
+}
+
+// SelfOrRole разрешает запрос, если email из URL-параметра param совпадает
+// с email пользователя, либо у пользователя одна из перечисленных ролей.
+func SelfOrRole(param string, roles ...models.Role) Policy {
+	return func(r *http.Request, id Identity) bool {
+		return chi.URLParam(r, param) == id.Email || id.HasRole(roles...)
+	}
+}
+
+// Authorize применяет политику к маршруту. Должен стоять после Middleware:
+// без Identity в контексте отвечает 401, при отказе политики — 403.
+func Authorize(policy Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := IdentityFromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !policy(r, id) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CanAccessOrder сообщает, может ли пользователь читать и менять заказ
+// покупателя ownerID. Используется там, где владельца можно узнать только
+// после загрузки заказа из базы.
+func CanAccessOrder(id Identity, ownerID int) bool {
+	return id.UserID == ownerID || id.HasRole(models.RoleAdmin, models.RoleStaff)
+}
+
+// CanAccessCart сообщает, может ли вызывающий работать с корзиной
+// пользователя ownerID. Анонимная корзина (ownerID == nil) доступна любому,
+// кто знает её ID.
+func CanAccessCart(id Identity, authenticated bool, ownerID *int) bool {
+	if ownerID == nil {
+		return true
+	}
+	return authenticated && CanAccessOrder(id, *ownerID)
+}
+
+// CanEditReview сообщает, может ли пользователь менять текст и оценку
+// отзыва автора authorID. Персонал отзывы не редактирует, только модерирует.
+func CanEditReview(id Identity, authorID int) bool {
+	return id.UserID == authorID
+}
+
+// CanDeleteReview сообщает, может ли пользователь удалить отзыв автора
+// authorID: сам автор или персонал.
+func CanDeleteReview(id Identity, authorID int) bool {
+	return id.UserID == authorID || id.HasRole(models.RoleAdmin, models.RoleStaff)
+}
+
+// CanAccessPet сообщает, может ли пользователь использовать профиль питомца
+// покупателя ownerID, например для подбора товаров в каталоге.
+func CanAccessPet(id Identity, ownerID int) bool {
+	return id.UserID == ownerID || id.HasRole(models.RoleAdmin, models.RoleStaff)
+}
+
+// CanViewCustomerSegments сообщает, может ли пользователь видеть
+// RFM-оценки и сегменты покупателей.
+func CanViewCustomerSegments(id Identity) bool {
+	return id.HasRole(models.RoleAdmin, models.RoleStaff)
+}
@@ -0,0 +1,144 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"go-pet-shop/internal/lib/auth"
+	"go-pet-shop/models"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+const (
+	Header       = "Idempotency-Key"
+	ReplayHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+type Store interface {
+	BeginIdempotent(userID int, key, requestHash string) (models.IdempotencyRecord, bool, error)
+	CompleteIdempotent(userID int, key string, statusCode int, contentType string, body []byte) error
+	AbortIdempotent(userID int, key string) error
+}
+
+// Middleware делает POST-запрос с заголовком Idempotency-Key повторяемым.
+// Ключ действует в пределах пользователя, поэтому middleware ставится после
+// auth.Middleware. Первый запрос выполняется и его ответ сохраняется;
+// повтор с тем же телом получает сохранённый ответ, с другим телом — 422,
+// а пока первый запрос ещё выполняется — 409.
+//
+// Ответ 500 не сохраняется: обработчики отвечают им, когда транзакция
+// откатилась, и запрос можно безопасно повторить.
+func Middleware(log *slog.Logger, store Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			id, ok := auth.IdentityFromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("idempotency_key", key),
+			)
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "invalid request", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			rec, created, err := store.BeginIdempotent(id.UserID, key, requestHash(r, body))
+			if err != nil {
+				log.Error("failed to begin idempotent request", slog.Any("error", err))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			if !created {
+				replay(w, r, rec, body)
+				return
+			}
+
+			rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			if rw.status == http.StatusInternalServerError {
+				if err := store.AbortIdempotent(id.UserID, key); err != nil {
+					log.Error("failed to release idempotency key", slog.Any("error", err))
+				}
+				return
+			}
+
+			if err := store.CompleteIdempotent(id.UserID, key, rw.status, rw.Header().Get("Content-Type"), rw.body.Bytes()); err != nil {
+				log.Error("failed to store idempotent response", slog.Any("error", err))
+			}
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, rec models.IdempotencyRecord, body []byte) {
+	if rec.RequestHash != requestHash(r, body) {
+		http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !rec.Completed {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "a request with this Idempotency-Key is in progress", http.StatusConflict)
+		return
+	}
+
+	if rec.ContentType != "" {
+		w.Header().Set("Content-Type", rec.ContentType)
+	}
+	w.Header().Set(ReplayHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	w.Write(rec.Body)
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder пропускает ответ клиенту и одновременно запоминает его.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rw *recorder) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,112 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"go-pet-shop/models"
+	"sync"
+	"time"
+)
+
+// Токены, которыми клиент управляет поведением FakeGateway.
+// Любой другой токен проходит успешно.
+const (
+	FakeTokenDecline = "tok_decline"
+	FakeTokenTimeout = "tok_timeout"
+	FakeTokenAsync   = "tok_async"
+)
+
+// FakeGateway — детерминированный шлюз в памяти процесса для dev и тестов.
+// Ссылки на платежи выдаются по порядку: fake_000001, fake_000002...
+// Для токена tok_async Authorize отвечает pending, а через AsyncDelay
+// отправляет подтверждение в функцию, заданную OnConfirm.
+type FakeGateway struct {
+	AsyncDelay time.Duration
+
+	mu        sync.Mutex
+	seq       int
+	payments  map[string]models.PaymentStatus
+	onConfirm func(Confirmation)
+}
+
+func NewFakeGateway(asyncDelay time.Duration) *FakeGateway {
+	return &FakeGateway{
+		AsyncDelay: asyncDelay,
+		payments:   map[string]models.PaymentStatus{},
+	}
+}
+
+// OnConfirm задаёт получателя асинхронных подтверждений.
+func (g *FakeGateway) OnConfirm(fn func(Confirmation)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onConfirm = fn
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	switch req.Token {
+	case FakeTokenTimeout:
+		return Result{}, ErrTimeout
+	case FakeTokenDecline:
+		return Result{Reference: g.register(models.PaymentDeclined), Status: models.PaymentDeclined}, ErrDeclined
+	case FakeTokenAsync:
+		ref := g.register(models.PaymentPending)
+		time.AfterFunc(g.AsyncDelay, func() { g.confirm(ref, models.PaymentAuthorized) })
+		return Result{Reference: ref, Status: models.PaymentPending}, nil
+	}
+
+	return Result{Reference: g.register(models.PaymentAuthorized), Status: models.PaymentAuthorized}, nil
+}
+
+func (g *FakeGateway) Capture(_ context.Context, ref string, _ models.Money) (Result, error) {
+	return g.move(ref, models.PaymentAuthorized, models.PaymentCaptured)
+}
+
+func (g *FakeGateway) Void(_ context.Context, ref string) (Result, error) {
+	return g.move(ref, models.PaymentAuthorized, models.PaymentVoided)
+}
+
+func (g *FakeGateway) Refund(_ context.Context, ref string, _ models.Money) (Result, error) {
+	return g.move(ref, models.PaymentCaptured, models.PaymentRefunded)
+}
+
+func (g *FakeGateway) register(status models.PaymentStatus) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.seq++
+	ref := fmt.Sprintf("fake_%06d", g.seq)
+	g.payments[ref] = status
+	return ref
+}
+
+func (g *FakeGateway) move(ref string, from, to models.PaymentStatus) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status, ok := g.payments[ref]
+	if !ok {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknown, ref)
+	}
+	if status != from {
+		return Result{Reference: ref, Status: status}, fmt.Errorf("payment %s is %s, expected %s", ref, status, from)
+	}
+
+	g.payments[ref] = to
+	return Result{Reference: ref, Status: to}, nil
+}
+
+func (g *FakeGateway) confirm(ref string, status models.PaymentStatus) {
+	g.mu.Lock()
+	g.payments[ref] = status
+	notify := g.onConfirm
+	g.mu.Unlock()
+
+	if notify != nil {
+		notify(Confirmation{Reference: ref, Status: status})
+	}
+}
@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"go-pet-shop/models"
+)
+
+var (
+	ErrDeclined = errors.New("payment declined")
+	ErrTimeout  = errors.New("payment gateway timeout")
+	ErrUnknown  = errors.New("unknown payment reference")
+)
+
+type AuthorizeRequest struct {
+	OrderID int
+	Amount  models.Money
+	// Token — одноразовый токен карты, полученный клиентом от провайдера.
+	Token string
+}
+
+// Result — ответ шлюза. Status == models.PaymentPending означает, что шлюз
+// подтвердит операцию позже через Confirmation.
+type Result struct {
+	Reference string
+	Status    models.PaymentStatus
+}
+
+// Confirmation — асинхронное уведомление шлюза о смене статуса платежа.
+type Confirmation struct {
+	Reference string
+	Status    models.PaymentStatus
+}
+
+// Gateway — платёжный провайдер. Authorize блокирует деньги, Capture
+// списывает заблокированное, Void снимает блокировку, Refund возвращает
+// списанное.
+type Gateway interface {
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, ref string, amount models.Money) (Result, error)
+	Void(ctx context.Context, ref string) (Result, error)
+	Refund(ctx context.Context, ref string, amount models.Money) (Result, error)
+}
@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/models"
+	"log/slog"
+)
+
+type Store interface {
+	GetOrderPayment(orderID int) (models.Payment, error)
+	GetPaymentByReference(ref string) (models.Payment, error)
+	UpdatePayment(paymentID int, status models.PaymentStatus, ref, detail string) (models.Payment, error)
+	GetOrderByID(id int) (models.Order, error)
+}
+
+// Processor проводит оплату заказа через Gateway и записывает каждый шаг
+// в transactions. Последствия для заказа (paid, отмена) применяет Store.
+type Processor struct {
+	log     *slog.Logger
+	gateway Gateway
+	store   Store
+}
+
+func NewProcessor(log *slog.Logger, gateway Gateway, store Store) *Processor {
+	return &Processor{log: log, gateway: gateway, store: store}
+}
+
+// Pay авторизует и сразу списывает оплату заказа. Если шлюз подтверждает
+// платёж асинхронно, возвращает платёж в статусе pending; списание произойдёт
+// в Confirm. При отказе возвращает ErrDeclined, при таймауте — ErrTimeout;
+// в обоих случаях заказ уже отменён.
+func (p *Processor) Pay(ctx context.Context, orderID int, token string) (models.Payment, error) {
+	const fn = "payment.Processor.Pay"
+
+	pay, err := p.store.GetOrderPayment(orderID)
+	if err != nil {
+		return pay, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	res, err := p.gateway.Authorize(ctx, AuthorizeRequest{OrderID: orderID, Amount: pay.Amount, Token: token})
+	switch {
+	case errors.Is(err, ErrDeclined):
+		return p.fail(pay, models.PaymentDeclined, res.Reference, err)
+	case err != nil:
+		return p.fail(pay, models.PaymentFailed, res.Reference, err)
+	}
+
+	pay, err = p.store.UpdatePayment(pay.ID, res.Status, res.Reference, "authorize")
+	if err != nil {
+		return pay, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if res.Status != models.PaymentAuthorized {
+		return pay, nil
+	}
+
+	return p.capture(ctx, pay)
+}
+
+// Confirm обрабатывает асинхронное подтверждение шлюза.
+func (p *Processor) Confirm(ctx context.Context, c Confirmation) error {
+	const fn = "payment.Processor.Confirm"
+
+	pay, err := p.store.GetPaymentByReference(c.Reference)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if pay.Status != models.PaymentPending {
+		// Повторное уведомление.
+		return nil
+	}
+
+	pay, err = p.store.UpdatePayment(pay.ID, c.Status, c.Reference, "confirmation")
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if c.Status == models.PaymentAuthorized {
+		if _, err := p.capture(ctx, pay); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	return nil
+}
+
+// Release возвращает деньги по отменённому заказу: снимает блокировку,
+// если оплата только авторизована, или делает возврат, если она списана.
+// Сторнирующую запись добавляет Store.UpdatePayment, когда возврат прошёл.
+// Для уже возвращённой оплаты ничего не делает, поэтому его можно повторять.
+func (p *Processor) Release(ctx context.Context, orderID int) error {
+	const fn = "payment.Processor.Release"
+
+	pay, err := p.store.GetOrderPayment(orderID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var res Result
+	switch pay.Status {
+	case models.PaymentAuthorized:
+		res, err = p.gateway.Void(ctx, pay.GatewayReference)
+	case models.PaymentCaptured:
+		res, err = p.gateway.Refund(ctx, pay.GatewayReference, pay.Amount)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if _, err := p.store.UpdatePayment(pay.ID, res.Status, res.Reference, "release"); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (p *Processor) capture(ctx context.Context, pay models.Payment) (models.Payment, error) {
+	res, err := p.gateway.Capture(ctx, pay.GatewayReference, pay.Amount)
+	if err != nil {
+		return p.fail(pay, models.PaymentFailed, pay.GatewayReference, err)
+	}
+
+	pay, err = p.store.UpdatePayment(pay.ID, res.Status, res.Reference, "capture")
+	if err != nil {
+		return pay, fmt.Errorf("payment.Processor.capture: %w", err)
+	}
+
+	// Асинхронное подтверждение могло прийти уже после того, как заказ
+	// отменили (например, истёк резерв остатков): деньги нужно вернуть.
+	order, err := p.store.GetOrderByID(pay.OrderID)
+	if err != nil {
+		return pay, fmt.Errorf("payment.Processor.capture: %w", err)
+	}
+	if order.Status == models.OrderCancelled {
+		p.log.Info("payment captured for cancelled order, refunding", slog.Int("order_id", pay.OrderID))
+		if err := p.Release(ctx, pay.OrderID); err != nil {
+			return pay, fmt.Errorf("payment.Processor.capture: %w", err)
+		}
+		return p.store.GetOrderPayment(pay.OrderID)
+	}
+
+	return pay, nil
+}
+
+func (p *Processor) fail(pay models.Payment, status models.PaymentStatus, ref string, cause error) (models.Payment, error) {
+	p.log.Info("payment failed",
+		slog.Int("order_id", pay.OrderID),
+		slog.String("status", string(status)),
+		slog.Any("error", cause),
+	)
+
+	updated, err := p.store.UpdatePayment(pay.ID, status, ref, cause.Error())
+	if err != nil {
+		return pay, fmt.Errorf("payment.Processor.fail: %w", errors.Join(cause, err))
+	}
+
+	return updated, cause
+}
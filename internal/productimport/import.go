@@ -0,0 +1,291 @@
+// Package productimport загружает каталог товаров из CSV и NDJSON.
+//
+// Колонки CSV и ключи NDJSON одинаковые: sku, name, price, stock
+// (обязательные), reorder_threshold, tax_class, weight_grams,
+// pet_min_weight_grams, pet_max_weight_grams, category_id и species.
+// В CSV виды животных перечисляются через «|», пустая ячейка означает, что
+// поле не задано. Товар ищется по sku: найденный обновляется, иначе
+// создаётся.
+package productimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-pet-shop/models"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFile — файл нельзя разобрать целиком: нет заголовка,
+// неизвестная колонка, сломанные кавычки.
+var ErrInvalidFile = errors.New("invalid import file")
+
+// Максимальная длина строки NDJSON.
+const maxLineSize = 1 << 20
+
+type Store interface {
+	ImportProducts(rows []models.ProductImportRow, commit bool, actorID *int) (models.ImportReport, error)
+}
+
+// Import разбирает файл и загружает строки в store одной транзакцией.
+// Если dryRun или хотя бы одна строка отклонена, каталог не меняется,
+// а отчёт показывает, что было бы создано, обновлено и отклонено.
+func Import(store Store, r io.Reader, format models.ImportFormat, dryRun bool, actorID *int) (models.ImportReport, error) {
+	rows, rejected, err := Parse(r, format)
+	if err != nil {
+		return models.ImportReport{}, err
+	}
+
+	report, err := store.ImportProducts(rows, !dryRun && len(rejected) == 0, actorID)
+	if err != nil {
+		return report, err
+	}
+
+	report.DryRun = dryRun
+	for _, res := range rejected {
+		report.Add(res)
+	}
+	sort.SliceStable(report.Rows, func(i, j int) bool { return report.Rows[i].Line < report.Rows[j].Line })
+
+	return report, nil
+}
+
+// Parse читает строки файла. Строки, которые не удалось разобрать,
+// и повторы sku возвращаются в rejected с номером строки.
+func Parse(r io.Reader, format models.ImportFormat) (rows []models.ProductImportRow, rejected []models.ImportResult, err error) {
+	var records []record
+	switch format {
+	case models.ImportCSV:
+		records, rejected, err = readCSV(r)
+	case models.ImportNDJSON:
+		records, rejected, err = readNDJSON(r)
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown format %q", ErrInvalidFile, format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := map[string]int{}
+	for _, rec := range records {
+		row, err := rec.row()
+		if err != nil {
+			rejected = append(rejected, models.ImportResult{Line: rec.line, SKU: row.SKU, Action: models.ImportReject, Error: err.Error()})
+			continue
+		}
+		if first, ok := seen[row.SKU]; ok {
+			rejected = append(rejected, models.ImportResult{Line: rec.line, SKU: row.SKU, Action: models.ImportReject,
+				Error: fmt.Sprintf("duplicate sku, first seen on line %d", first)})
+			continue
+		}
+		seen[row.SKU] = rec.line
+		rows = append(rows, row)
+	}
+
+	return rows, rejected, nil
+}
+
+// record — строка файла до проверки обязательных полей.
+type record struct {
+	line              int
+	SKU               *string          `json:"sku"`
+	Name              *string          `json:"name"`
+	Price             *models.Money    `json:"price"`
+	Stock             *int             `json:"stock"`
+	ReorderThreshold  *int             `json:"reorder_threshold"`
+	TaxClass          *models.TaxClass `json:"tax_class"`
+	WeightGrams       *int             `json:"weight_grams"`
+	PetMinWeightGrams *int             `json:"pet_min_weight_grams"`
+	PetMaxWeightGrams *int             `json:"pet_max_weight_grams"`
+	CategoryID        *int             `json:"category_id"`
+	Species           []models.Species `json:"species"`
+}
+
+func (rec record) row() (models.ProductImportRow, error) {
+	row := models.ProductImportRow{
+		Line:              rec.line,
+		ReorderThreshold:  rec.ReorderThreshold,
+		TaxClass:          rec.TaxClass,
+		WeightGrams:       rec.WeightGrams,
+		PetMinWeightGrams: rec.PetMinWeightGrams,
+		PetMaxWeightGrams: rec.PetMaxWeightGrams,
+		CategoryID:        rec.CategoryID,
+		Species:           rec.Species,
+	}
+	if rec.SKU != nil {
+		row.SKU = strings.TrimSpace(*rec.SKU)
+	}
+	if row.SKU == "" {
+		return row, errors.New("sku is required")
+	}
+	if rec.Name == nil || rec.Price == nil || rec.Stock == nil {
+		return row, errors.New("name, price and stock are required")
+	}
+	row.Name = strings.TrimSpace(*rec.Name)
+	row.Price = *rec.Price
+	row.Stock = *rec.Stock
+	return row, nil
+}
+
+var csvColumns = map[string]bool{
+	"sku": true, "name": true, "price": true, "stock": true, "reorder_threshold": true, "tax_class": true,
+	"weight_grams": true, "pet_min_weight_grams": true, "pet_max_weight_grams": true, "category_id": true, "species": true,
+}
+
+func readCSV(r io.Reader) ([]record, []models.ImportResult, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("%w: empty file", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !csvColumns[name] {
+			return nil, nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFile, name)
+		}
+		if slices.Contains(header[:i], name) {
+			return nil, nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidFile, name)
+		}
+		header[i] = name
+	}
+	for _, name := range []string{"sku", "name", "price", "stock"} {
+		if !slices.Contains(header, name) {
+			return nil, nil, fmt.Errorf("%w: missing column %q", ErrInvalidFile, name)
+		}
+	}
+
+	var records []record
+	var rejected []models.ImportResult
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			rejected = append(rejected, models.ImportResult{Line: line, Action: models.ImportReject,
+				Error: fmt.Sprintf("expected %d fields, got %d", len(header), len(fields))})
+			continue
+		}
+
+		rec, err := csvRecord(header, fields)
+		rec.line = line
+		if err != nil {
+			rejected = append(rejected, models.ImportResult{Line: line, SKU: derefString(rec.SKU), Action: models.ImportReject, Error: err.Error()})
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	return records, rejected, nil
+}
+
+// csvRecord переводит ячейки CSV в record. Пустые ячейки пропускаются.
+func csvRecord(header, fields []string) (record, error) {
+	var rec record
+	for i, value := range fields {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		var err error
+		switch column := header[i]; column {
+		case "sku":
+			rec.SKU = &value
+		case "name":
+			rec.Name = &value
+		case "price":
+			var price models.Money
+			if price, err = models.ParseMoney(value); err == nil {
+				rec.Price = &price
+			}
+		case "tax_class":
+			class := models.TaxClass(value)
+			rec.TaxClass = &class
+		case "species":
+			rec.Species = []models.Species{}
+			for _, code := range strings.Split(value, "|") {
+				if code = strings.TrimSpace(code); code != "" {
+					rec.Species = append(rec.Species, models.Species(code))
+				}
+			}
+		default:
+			var n int
+			if n, err = strconv.Atoi(value); err == nil {
+				*intField(&rec, column) = &n
+			}
+		}
+		if err != nil {
+			return rec, fmt.Errorf("invalid %s %q", header[i], value)
+		}
+	}
+	return rec, nil
+}
+
+// intField возвращает поле record для целочисленной колонки CSV.
+func intField(rec *record, column string) **int {
+	switch column {
+	case "stock":
+		return &rec.Stock
+	case "reorder_threshold":
+		return &rec.ReorderThreshold
+	case "weight_grams":
+		return &rec.WeightGrams
+	case "pet_min_weight_grams":
+		return &rec.PetMinWeightGrams
+	case "pet_max_weight_grams":
+		return &rec.PetMaxWeightGrams
+	}
+	return &rec.CategoryID
+}
+
+func readNDJSON(r io.Reader) ([]record, []models.ImportResult, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var records []record
+	var rejected []models.ImportResult
+	for line := 1; sc.Scan(); line++ {
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		rec := record{line: line}
+		if err := dec.Decode(&rec); err != nil {
+			rejected = append(rejected, models.ImportResult{Line: line, Action: models.ImportReject, Error: err.Error()})
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	return records, rejected, nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
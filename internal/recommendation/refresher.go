@@ -0,0 +1,52 @@
+package recommendation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Store interface {
+	RefreshRecommendations(minSupport int) (int64, error)
+}
+
+// Refresher периодически пересчитывает таблицу «покупают вместе»
+// по истории заказов. Между пересчётами рекомендации читаются из готовой
+// таблицы и не нагружают order_items.
+type Refresher struct {
+	log        *slog.Logger
+	store      Store
+	minSupport int
+	interval   time.Duration
+}
+
+func NewRefresher(log *slog.Logger, store Store, minSupport int, interval time.Duration) *Refresher {
+	return &Refresher{log: log, store: store, minSupport: minSupport, interval: interval}
+}
+
+// Run пересчитывает рекомендации сразу и затем раз в interval до отмены ctx.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.Refresh()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh выполняет один пересчёт.
+func (r *Refresher) Refresh() {
+	start := time.Now()
+	pairs, err := r.store.RefreshRecommendations(r.minSupport)
+	if err != nil {
+		r.log.Error("failed to refresh recommendations", slog.Any("error", err))
+		return
+	}
+	r.log.Info("recommendations refreshed", slog.Int64("pairs", pairs), slog.Duration("took", time.Since(start)))
+}
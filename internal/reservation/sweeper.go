@@ -0,0 +1,92 @@
+package reservation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Сколько заказов снимается за один проход.
+const sweepBatch = 100
+
+type Store interface {
+	ExpireReservations(limit int) ([]int, error)
+	GetUnreleasedPayments(olderThan time.Duration, limit int) ([]int, error)
+}
+
+// Payments возвращает деньги по отменённому заказу, см. payment.Processor.
+type Payments interface {
+	Release(ctx context.Context, orderID int) error
+}
+
+// Sweeper периодически снимает резервы остатков, не оплаченные за TTL:
+// заказ отменяется, товар снова доступен, авторизованная оплата снимается.
+// Он же повторяет возврат оплаты по отменённым заказам, если шлюз не ответил.
+type Sweeper struct {
+	log      *slog.Logger
+	store    Store
+	payments Payments
+	interval time.Duration
+}
+
+func NewSweeper(log *slog.Logger, store Store, payments Payments, interval time.Duration) *Sweeper {
+	return &Sweeper{log: log, store: store, payments: payments, interval: interval}
+}
+
+// Run работает до отмены ctx.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+			s.retryReleases(ctx)
+		}
+	}
+}
+
+func (s *Sweeper) sweep(ctx context.Context) {
+	for {
+		expired, err := s.store.ExpireReservations(sweepBatch)
+		if err != nil {
+			s.log.Error("failed to expire reservations", slog.Any("error", err))
+		}
+
+		for _, orderID := range expired {
+			s.log.Info("reservation expired, order cancelled", slog.Int("order_id", orderID))
+			if err := s.payments.Release(ctx, orderID); err != nil {
+				s.log.Error("failed to release payment", slog.Int("order_id", orderID), slog.Any("error", err))
+			}
+		}
+
+		// Неполная пачка — просроченных резервов больше нет.
+		if err != nil || len(expired) < sweepBatch || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// retryReleases повторяет возврат оплаты по заказам, отменённым хотя бы
+// интервал назад, деньги по которым так и не вернулись.
+func (s *Sweeper) retryReleases(ctx context.Context) {
+	orderIDs, err := s.store.GetUnreleasedPayments(s.interval, sweepBatch)
+	if err != nil {
+		s.log.Error("failed to list unreleased payments", slog.Any("error", err))
+		return
+	}
+
+	for _, orderID := range orderIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.payments.Release(ctx, orderID); err != nil {
+			s.log.Error("failed to retry payment release", slog.Int("order_id", orderID), slog.Any("error", err))
+			continue
+		}
+		s.log.Info("payment released on retry", slog.Int("order_id", orderID))
+	}
+}
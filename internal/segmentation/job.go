@@ -0,0 +1,52 @@
+package segmentation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Store interface {
+	RefreshCustomerSegments(window, newWindow time.Duration) (int64, error)
+}
+
+// Job периодически пересчитывает RFM-оценки и сегменты покупателей.
+// Запросы читают готовую таблицу и не агрегируют заказы на лету.
+type Job struct {
+	log       *slog.Logger
+	store     Store
+	window    time.Duration
+	newWindow time.Duration
+	interval  time.Duration
+}
+
+func NewJob(log *slog.Logger, store Store, window, newWindow, interval time.Duration) *Job {
+	return &Job{log: log, store: store, window: window, newWindow: newWindow, interval: interval}
+}
+
+// Run пересчитывает сегменты сразу и затем раз в interval до отмены ctx.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.Refresh()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh выполняет один пересчёт.
+func (j *Job) Refresh() {
+	start := time.Now()
+	customers, err := j.store.RefreshCustomerSegments(j.window, j.newWindow)
+	if err != nil {
+		j.log.Error("failed to refresh customer segments", slog.Any("error", err))
+		return
+	}
+	j.log.Info("customer segments refreshed", slog.Int64("customers", customers), slog.Duration("took", time.Since(start)))
+}
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const addressColumns = `id, user_id, label, recipient, phone, country, city, postal_code, line1, line2, is_default, created_at`
+
+func scanAddress(row scanner, a *models.Address) error {
+	return row.Scan(&a.ID, &a.UserID, &a.Label, &a.Recipient, &a.Phone, &a.Country, &a.City,
+		&a.PostalCode, &a.Line1, &a.Line2, &a.IsDefault, &a.CreatedAt)
+}
+
+// userIDByEmail находит пользователя адресной книги.
+func userIDByEmail(ctx context.Context, q querier, email string) (int, error) {
+	var id int
+	err := q.QueryRow(ctx, `SELECT id FROM users WHERE email = $1`, email).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrUserNotFound
+	}
+	return id, err
+}
+
+// GetAddresses возвращает адресную книгу пользователя, адрес по умолчанию
+// первым.
+func (s *Storage) GetAddresses(email string) ([]models.Address, error) {
+	const fn = "storage.postgres.address.GetAddresses"
+
+	ctx := context.Background()
+	userID, err := userIDByEmail(ctx, s.db, email)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+addressColumns+` FROM addresses
+		WHERE user_id = $1
+		ORDER BY is_default DESC, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	addresses := []models.Address{}
+	for rows.Next() {
+		var a models.Address
+		if err := scanAddress(rows, &a); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		addresses = append(addresses, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return addresses, nil
+}
+
+// CreateAddress добавляет адрес в книгу пользователя. Первый адрес
+// становится адресом по умолчанию.
+func (s *Storage) CreateAddress(email string, a models.Address) (models.Address, error) {
+	const fn = "storage.postgres.address.CreateAddress"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if a.UserID, err = userIDByEmail(ctx, tx, email); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := lockAddressBook(ctx, tx, a.UserID); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var count int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM addresses WHERE user_id = $1`, a.UserID).Scan(&count)
+	if err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+	a.IsDefault = a.IsDefault || count == 0
+
+	if err := clearDefaultAddress(ctx, tx, a); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO addresses (user_id, label, recipient, phone, country, city, postal_code, line1, line2, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`,
+		a.UserID, a.Label, a.Recipient, a.Phone, a.Country, a.City, a.PostalCode, a.Line1, a.Line2, a.IsDefault).
+		Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return a, nil
+}
+
+// UpdateAddress заменяет адрес a.ID в книге пользователя.
+func (s *Storage) UpdateAddress(email string, a models.Address) (models.Address, error) {
+	const fn = "storage.postgres.address.UpdateAddress"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if a.UserID, err = userIDByEmail(ctx, tx, email); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+	if err := lockAddressBook(ctx, tx, a.UserID); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := clearDefaultAddress(ctx, tx, a); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE addresses
+		SET label = $3, recipient = $4, phone = $5, country = $6, city = $7,
+		    postal_code = $8, line1 = $9, line2 = $10, is_default = $11
+		WHERE id = $1 AND user_id = $2
+		RETURNING created_at`,
+		a.ID, a.UserID, a.Label, a.Recipient, a.Phone, a.Country, a.City, a.PostalCode, a.Line1, a.Line2, a.IsDefault).
+		Scan(&a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return a, fmt.Errorf("%s: %w", fn, storage.ErrAddressNotFound)
+	}
+	if err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return a, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return a, nil
+}
+
+// lockAddressBook блокирует строку пользователя, чтобы параллельные правки
+// его книги не оставили два адреса по умолчанию.
+func lockAddressBook(ctx context.Context, tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID)
+	return err
+}
+
+// clearDefaultAddress снимает отметку «по умолчанию» с других адресов
+// пользователя, если a становится адресом по умолчанию.
+func clearDefaultAddress(ctx context.Context, tx pgx.Tx, a models.Address) error {
+	if !a.IsDefault {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE addresses SET is_default = FALSE
+		WHERE user_id = $1 AND id <> $2 AND is_default`, a.UserID, a.ID)
+	return err
+}
+
+// DeleteAddress удаляет адрес из книги. Оформленные заказы хранят копию адреса.
+func (s *Storage) DeleteAddress(email string, id int) error {
+	const fn = "storage.postgres.address.DeleteAddress"
+
+	tag, err := s.db.Exec(context.Background(), `
+		DELETE FROM addresses a
+		USING users u
+		WHERE a.id = $1 AND a.user_id = u.id AND u.email = $2`, id, email)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrAddressNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// paidOrder — условие «заказ оплачен»: по нему прошёл хотя бы один
+// завершённый платёж. Отмена и возврат после оплаты заказ из выручки
+// не убирают, отчёты считают валовую выручку.
+func paidOrder(alias string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM transactions t
+		WHERE t.order_id = %s.id AND t.status IN ('completed', 'captured'))`, alias)
+}
+
+// localTime переводит orders.created_at (TIMESTAMP в часовом поясе сессии)
+// в местное время пояса, переданного параметром tzParam.
+func localTime(column, tzParam string) string {
+	return fmt.Sprintf(`((%s AT TIME ZONE current_setting('TimeZone')) AT TIME ZONE %s)`, column, tzParam)
+}
+
+// GetRevenue строит временной ряд выручки по оплаченным заказам. Заказ
+// попадает в период по дате оформления в часовом поясе q.Timezone.
+func (s *Storage) GetRevenue(q models.RevenueQuery) (models.RevenueReport, error) {
+	const fn = "storage.postgres.analytics.GetRevenue"
+
+	report := models.RevenueReport{
+		From:        q.From.Format(time.DateOnly),
+		To:          q.To.Format(time.DateOnly),
+		Granularity: q.Granularity,
+		Timezone:    q.Timezone,
+		Buckets:     []models.RevenueBucket{},
+	}
+
+	created := localTime("o.created_at", "$4")
+	rows, err := s.db.Query(context.Background(), `
+		WITH paid AS (
+			SELECT o.id, o.total_price, date_trunc($3, `+created+`) AS bucket
+			FROM orders o
+			WHERE `+paidOrder("o")+`
+			  AND `+created+` >= $1::date
+			  AND `+created+` < $2::date + 1
+		),
+		units AS (
+			SELECT oi.order_id, SUM(oi.quantity) AS n
+			FROM order_items oi
+			JOIN paid ON paid.id = oi.order_id
+			GROUP BY oi.order_id
+		),
+		buckets AS (
+			SELECT generate_series(date_trunc($3, $1::date::timestamp), date_trunc($3, $2::date::timestamp),
+			                       ('1 ' || $3)::interval) AS bucket
+		)
+		SELECT to_char(b.bucket, 'YYYY-MM-DD'), COALESCE(SUM(p.total_price), 0), COUNT(p.id),
+		       COALESCE(SUM(u.n), 0)
+		FROM buckets b
+		LEFT JOIN paid p ON p.bucket = b.bucket
+		LEFT JOIN units u ON u.order_id = p.id
+		GROUP BY b.bucket
+		ORDER BY b.bucket`,
+		report.From, report.To, string(q.Granularity), q.Timezone)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.RevenueBucket
+		if err := rows.Scan(&b.Start, &b.GrossRevenue, &b.OrderCount, &b.UnitsSold); err != nil {
+			return report, fmt.Errorf("%s: %w", fn, err)
+		}
+		b.AverageOrderValue = models.AverageOrderValue(b.GrossRevenue, b.OrderCount)
+		report.Buckets = append(report.Buckets, b)
+
+		report.Total.GrossRevenue += b.GrossRevenue
+		report.Total.OrderCount += b.OrderCount
+		report.Total.UnitsSold += b.UnitsSold
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	report.Total.Start = report.From
+	report.Total.AverageOrderValue = models.AverageOrderValue(report.Total.GrossRevenue, report.Total.OrderCount)
+
+	return report, nil
+}
+
+// GetPopularProducts возвращает q.Limit самых продаваемых товаров по оплаченным
+// заказам за период q.From–q.To. Место каждого товара сравнивается с его местом
+// за предыдущий период той же длины.
+func (s *Storage) GetPopularProducts(q models.PopularQuery) ([]models.PopularProduct, error) {
+	const fn = "storage.postgres.analytics.GetPopularProducts"
+
+	where := []string{paidOrder("o")}
+	args := []any{q.Limit}
+	current := "TRUE"
+
+	if !q.From.IsZero() {
+		days := int(q.To.Sub(q.From).Hours()/24) + 1
+		args = append(args, q.Timezone,
+			q.From.AddDate(0, 0, -days).Format(time.DateOnly),
+			q.From.Format(time.DateOnly),
+			q.To.Format(time.DateOnly))
+		created := localTime("o.created_at", "$2")
+		where = append(where, created+" >= $3::date", created+" < $5::date + 1")
+		current = created + " >= $4::date"
+	}
+	if q.CategoryID != nil {
+		args = append(args, *q.CategoryID)
+		where = append(where, "p.category_id IN "+categorySubtree(len(args)))
+	}
+
+	metric := "units"
+	if q.Metric == models.PopularityRevenue {
+		metric = "revenue"
+	}
+
+	query := fmt.Sprintf(`
+		WITH totals AS (
+			SELECT oi.product_id, %s AS current,
+			       SUM(oi.quantity) AS units,
+			       SUM(oi.quantity * COALESCE(oi.unit_price, p.price)) AS revenue
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			JOIN products p ON p.id = oi.product_id
+			%s
+			GROUP BY 1, 2
+		),
+		ranked AS (
+			SELECT totals.*, ROW_NUMBER() OVER (PARTITION BY current ORDER BY %s DESC, product_id) AS rank
+			FROM totals
+		)
+		SELECT c.product_id, p.name, c.units, c.revenue, c.rank, prev.rank
+		FROM ranked c
+		JOIN products p ON p.id = c.product_id
+		LEFT JOIN ranked prev ON prev.product_id = c.product_id AND NOT prev.current
+		WHERE c.current AND c.rank <= $1
+		ORDER BY c.rank`, current, whereClause(where), metric)
+
+	rows, err := s.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	popular := []models.PopularProduct{}
+	for rows.Next() {
+		var p models.PopularProduct
+		if err := rows.Scan(&p.ID, &p.Name, &p.TotalSold, &p.Revenue, &p.Rank, &p.PreviousRank); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		if p.PreviousRank != nil {
+			change := *p.PreviousRank - p.Rank
+			p.RankChange = &change
+		}
+		popular = append(popular, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return popular, nil
+}
+
+const customerRFMColumns = `c.user_id, u.email, u.name, c.recency_days, c.frequency, c.monetary,
+	c.r_score, c.f_score, c.m_score, c.lifetime_value, c.order_count,
+	c.first_order_at, c.last_order_at, c.segment, c.computed_at`
+
+const customerRFMFrom = ` FROM customer_rfm c JOIN users u ON u.id = c.user_id`
+
+func scanCustomerRFM(row scanner, c *models.CustomerRFM) error {
+	return row.Scan(&c.UserID, &c.Email, &c.Name, &c.RecencyDays, &c.Frequency, &c.Monetary,
+		&c.RecencyScore, &c.FrequencyScore, &c.MonetaryScore, &c.LifetimeValue, &c.OrderCount,
+		&c.FirstOrderAt, &c.LastOrderAt, &c.Segment, &c.ComputedAt)
+}
+
+// RefreshCustomerSegments пересчитывает customer_rfm по оплаченным, не
+// отменённым и не возвращённым заказам. Frequency и Monetary считаются
+// за последние window, покупатели с первым заказом моложе newWindow
+// получают сегмент new. Возвращает число покупателей.
+func (s *Storage) RefreshCustomerSegments(window, newWindow time.Duration) (int64, error) {
+	const fn = "storage.postgres.analytics.RefreshCustomerSegments"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM customer_rfm`); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		WITH paid AS (
+			SELECT o.user_id, o.total_price, o.created_at
+			FROM orders o
+			WHERE `+paidOrder("o")+` AND o.status NOT IN ('cancelled', 'refunded')
+		),
+		customers AS (
+			SELECT user_id,
+			       MIN(created_at) AS first_order_at,
+			       MAX(created_at) AS last_order_at,
+			       COUNT(*) AS order_count,
+			       SUM(total_price) AS lifetime_value,
+			       COUNT(*) FILTER (WHERE created_at >= LOCALTIMESTAMP - $1::bigint * INTERVAL '1 second') AS frequency,
+			       COALESCE(SUM(total_price) FILTER (WHERE created_at >= LOCALTIMESTAMP - $1::bigint * INTERVAL '1 second'), 0) AS monetary
+			FROM paid
+			GROUP BY user_id
+		),
+		scored AS (
+			SELECT customers.*,
+			       NTILE(5) OVER (ORDER BY last_order_at) AS r_score,
+			       NTILE(5) OVER (ORDER BY frequency, order_count) AS f_score,
+			       NTILE(5) OVER (ORDER BY monetary, lifetime_value) AS m_score
+			FROM customers
+		)
+		INSERT INTO customer_rfm (user_id, recency_days, frequency, monetary, r_score, f_score, m_score,
+		                          lifetime_value, order_count, first_order_at, last_order_at, segment)
+		SELECT user_id, LOCALTIMESTAMP::date - last_order_at::date, frequency, monetary, r_score, f_score, m_score,
+		       lifetime_value, order_count, first_order_at, last_order_at,
+		       CASE
+		           WHEN first_order_at >= LOCALTIMESTAMP - $2::bigint * INTERVAL '1 second' THEN 'new'
+		           WHEN r_score >= 4 AND f_score >= 4 THEN 'champion'
+		           WHEN r_score <= 2 AND f_score >= 3 THEN 'at-risk'
+		           WHEN r_score <= 2 THEN 'lost'
+		           ELSE 'regular'
+		       END
+		FROM scored`, int64(window.Seconds()), int64(newWindow.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetCustomerSegments возвращает страницу покупателей с RFM-оценками,
+// по убыванию LifetimeValue.
+func (s *Storage) GetCustomerSegments(q models.CustomerRFMQuery) ([]models.CustomerRFM, error) {
+	const fn = "storage.postgres.analytics.GetCustomerSegments"
+
+	args := []any{q.Limit, q.Offset}
+	where := ""
+	if q.Segment != "" {
+		args = append(args, q.Segment)
+		where = " WHERE c.segment = $3"
+	}
+
+	rows, err := s.db.Query(context.Background(), `SELECT `+customerRFMColumns+customerRFMFrom+where+`
+		ORDER BY c.lifetime_value DESC, c.user_id
+		LIMIT $1 OFFSET $2`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	customers := []models.CustomerRFM{}
+	for rows.Next() {
+		var c models.CustomerRFM
+		if err := scanCustomerRFM(rows, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		customers = append(customers, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return customers, nil
+}
+
+// GetCustomerRFM возвращает RFM-оценки покупателя. nil — оценок нет:
+// у пользователя нет оплаченных заказов или пересчёта ещё не было.
+func (s *Storage) GetCustomerRFM(userID int) (*models.CustomerRFM, error) {
+	const fn = "storage.postgres.analytics.GetCustomerRFM"
+
+	var c models.CustomerRFM
+	err := scanCustomerRFM(s.db.QueryRow(context.Background(),
+		`SELECT `+customerRFMColumns+customerRFMFrom+` WHERE c.user_id = $1`, userID), &c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,275 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const cartColumns = `id::text, user_id, status, order_id, created_at, updated_at`
+
+func scanCart(row scanner, c *models.Cart) error {
+	return row.Scan(&c.ID, &c.UserID, &c.Status, &c.OrderID, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// CreateCart создаёт пустую корзину. userID == nil — анонимная корзина.
+func (s *Storage) CreateCart(userID *int) (models.Cart, error) {
+	const fn = "storage.postgres.cart.CreateCart"
+
+	var cart models.Cart
+	err := scanCart(s.db.QueryRow(context.Background(), `
+		INSERT INTO carts (user_id) VALUES ($1)
+		RETURNING `+cartColumns, userID), &cart)
+	if err != nil {
+		return cart, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return cart, nil
+}
+
+func (s *Storage) GetCart(cartID string) (models.Cart, error) {
+	const fn = "storage.postgres.cart.GetCart"
+
+	var cart models.Cart
+	err := scanCart(s.db.QueryRow(context.Background(),
+		`SELECT `+cartColumns+` FROM carts WHERE id = $1`, cartID), &cart)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return cart, fmt.Errorf("%s: %w", fn, storage.ErrCartNotFound)
+	}
+	if err != nil {
+		return cart, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return cart, nil
+}
+
+// GetCartView возвращает корзину с текущими ценами и остатками товаров
+// и вариантов.
+func (s *Storage) GetCartView(cartID string) (models.CartView, error) {
+	const fn = "storage.postgres.cart.GetCartView"
+
+	cart, err := s.GetCart(cartID)
+	if err != nil {
+		return models.CartView{}, err
+	}
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT ci.id, ci.cart_id::text, ci.product_id, ci.variant_id, ci.quantity,
+		       p.name, COALESCE(v.sku, ''), COALESCE(v.price, p.price),
+		       CASE WHEN v.id IS NULL THEN `+availableProductStock("p")+` ELSE `+availableVariantStock("v")+` END
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.id`, cartID)
+	if err != nil {
+		return models.CartView{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	var lines []models.CartLine
+	for rows.Next() {
+		var l models.CartLine
+		err := rows.Scan(&l.ID, &l.CartID, &l.ProductID, &l.VariantID, &l.Quantity,
+			&l.ProductName, &l.SKU, &l.UnitPrice, &l.Available)
+		if err != nil {
+			return models.CartView{}, fmt.Errorf("%s: %w", fn, err)
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return models.CartView{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return models.NewCartView(cart, lines), nil
+}
+
+// AddCartItem добавляет позицию в корзину. Если такой товар (вариант) уже
+// есть, количество увеличивается. Остаток здесь не проверяется: нехватку
+// показывают предупреждения в GetCartView, а окончательно — оформление.
+func (s *Storage) AddCartItem(item models.CartItem) (models.CartItem, error) {
+	const fn = "storage.postgres.cart.AddCartItem"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return item, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOpenCart(ctx, tx, item.CartID); err != nil {
+		return item, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var hasVariants, variantOK bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM product_variants WHERE product_id = p.id),
+		       $2::int IS NULL OR EXISTS (SELECT 1 FROM product_variants WHERE id = $2 AND product_id = p.id)
+		FROM products p WHERE p.id = $1`,
+		item.ProductID, item.VariantID).Scan(&hasVariants, &variantOK)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return item, fmt.Errorf("%s: %w", fn, storage.ErrProductNotFound)
+	}
+	if err != nil {
+		return item, fmt.Errorf("%s: %w", fn, err)
+	}
+	if !variantOK {
+		return item, fmt.Errorf("%s: %w", fn, storage.ErrVariantNotFound)
+	}
+	if hasVariants && item.VariantID == nil {
+		return item, fmt.Errorf("%s: %w", fn, storage.ErrVariantRequired)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cart_id, product_id, COALESCE(variant_id, 0))
+		DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+		RETURNING id, quantity`,
+		item.CartID, item.ProductID, item.VariantID, item.Quantity).Scan(&item.ID, &item.Quantity)
+	if err != nil {
+		return item, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := touchCart(ctx, tx, item.CartID); err != nil {
+		return item, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return item, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return item, nil
+}
+
+// UpdateCartItem задаёт новое количество позиции корзины.
+func (s *Storage) UpdateCartItem(cartID string, itemID, quantity int) error {
+	const fn = "storage.postgres.cart.UpdateCartItem"
+
+	return s.changeCartItem(fn, cartID, itemID, `UPDATE cart_items SET quantity = $3 WHERE cart_id = $1 AND id = $2`, quantity)
+}
+
+func (s *Storage) DeleteCartItem(cartID string, itemID int) error {
+	const fn = "storage.postgres.cart.DeleteCartItem"
+
+	return s.changeCartItem(fn, cartID, itemID, `DELETE FROM cart_items WHERE cart_id = $1 AND id = $2`)
+}
+
+// changeCartItem выполняет запрос к одной позиции открытой корзины.
+// Параметры запроса: $1 — cartID, $2 — itemID, дальше args.
+func (s *Storage) changeCartItem(fn, cartID string, itemID int, query string, args ...any) error {
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOpenCart(ctx, tx, cartID); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	tag, err := tx.Exec(ctx, query, append([]any{cartID, itemID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrCartItemNotFound)
+	}
+
+	if err := touchCart(ctx, tx, cartID); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// CheckoutCart оформляет корзину в заказ пользователя userID той же логикой,
+// что и PlaceOrder, и закрывает корзину. Анонимная корзина при этом
+// закрепляется за пользователем. Повторное оформление даёт ErrCartClosed.
+// Позиции берутся из корзины, draft.Items игнорируется. Остальное берётся из
+// draft: промокод, регион налога, адрес и способ доставки, питомец.
+func (s *Storage) CheckoutCart(cartID string, userID int, draft models.OrderDraft) (int, error) {
+	const fn = "storage.postgres.cart.CheckoutCart"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOpenCart(ctx, tx, cartID); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT product_id, variant_id, quantity
+		FROM cart_items WHERE cart_id = $1
+		ORDER BY id`, cartID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OrderItem, error) {
+		var item models.OrderItem
+		err := row.Scan(&item.ProductID, &item.VariantID, &item.Quantity)
+		return item, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	draft.Items = items
+	if draft.TaxRegion == "" {
+		draft.TaxRegion = s.taxRegion
+	}
+	orderID, err := placeOrder(ctx, tx, userID, draft, s.reservationTTL)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE carts
+		SET status = $2, user_id = $3, order_id = $4, updated_at = NOW()
+		WHERE id = $1`,
+		cartID, models.CartCheckedOut, userID, orderID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return orderID, nil
+}
+
+// lockOpenCart блокирует корзину до конца транзакции, чтобы изменения
+// позиций не пересекались с оформлением.
+func lockOpenCart(ctx context.Context, tx pgx.Tx, cartID string) error {
+	var status models.CartStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM carts WHERE id = $1 FOR UPDATE`, cartID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrCartNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.CartOpen {
+		return storage.ErrCartClosed
+	}
+	return nil
+}
+
+func touchCart(ctx context.Context, tx pgx.Tx, cartID string) error {
+	_, err := tx.Exec(ctx, `UPDATE carts SET updated_at = NOW() WHERE id = $1`, cartID)
+	return err
+}
@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func (s *Storage) GetAllCategories() ([]models.Category, error) {
+	const fn = "storage.postgres.category.GetAllCategories"
+
+	rows, err := s.db.Query(context.Background(),
+		`SELECT id, parent_id, name, slug FROM categories ORDER BY parent_id NULLS FIRST, name`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return categories, nil
+}
+
+func (s *Storage) GetCategoryByID(id int) (models.Category, error) {
+	const fn = "storage.postgres.category.GetCategoryByID"
+
+	var c models.Category
+	err := s.db.QueryRow(context.Background(),
+		`SELECT id, parent_id, name, slug FROM categories WHERE id = $1`, id).
+		Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, fmt.Errorf("%s: %w", fn, storage.ErrCategoryNotFound)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return c, nil
+}
+
+func (s *Storage) CreateCategory(c models.Category) (int, error) {
+	const fn = "storage.postgres.category.CreateCategory"
+
+	var id int
+	err := s.db.QueryRow(context.Background(),
+		`INSERT INTO categories (parent_id, name, slug) VALUES ($1, $2, $3) RETURNING id`,
+		c.ParentID, c.Name, c.Slug).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, categoryError(err))
+	}
+
+	return id, nil
+}
+
+func (s *Storage) UpdateCategory(c models.Category) error {
+	const fn = "storage.postgres.category.UpdateCategory"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Новый родитель не должен лежать в поддереве самой категории.
+	if c.ParentID != nil {
+		// Сначала блокируем категорию и цепочку предков нового родителя
+		// в порядке id. Встречные перемещения (A под B и B под A) пересекаются
+		// по этим строкам и идут по очереди, а проверка ниже видит результат
+		// первого из них.
+		_, err = tx.Exec(ctx, `
+			WITH RECURSIVE ancestors AS (
+				SELECT id, parent_id FROM categories WHERE id = $2
+				UNION
+				SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+			)
+			SELECT id FROM categories
+			WHERE id = $1 OR id IN (SELECT id FROM ancestors)
+			ORDER BY id
+			FOR UPDATE`,
+			c.ID, *c.ParentID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+
+		var cycle bool
+		err = tx.QueryRow(ctx, `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $1
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+			)
+			SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`,
+			c.ID, *c.ParentID).Scan(&cycle)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		if cycle {
+			return fmt.Errorf("%s: %w", fn, storage.ErrCategoryCycle)
+		}
+	}
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE categories SET parent_id = $1, name = $2, slug = $3 WHERE id = $4`,
+		c.ParentID, c.Name, c.Slug, c.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, categoryError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrCategoryNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteCategory(id int) error {
+	const fn = "storage.postgres.category.DeleteCategory"
+
+	tag, err := s.db.Exec(context.Background(), `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return fmt.Errorf("%s: %w", fn, storage.ErrCategoryHasChildren)
+		}
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrCategoryNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetAllSpecies() ([]models.SpeciesInfo, error) {
+	const fn = "storage.postgres.category.GetAllSpecies"
+
+	rows, err := s.db.Query(context.Background(), `SELECT id, code, name FROM species ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	species := []models.SpeciesInfo{}
+	for rows.Next() {
+		var sp models.SpeciesInfo
+		if err := rows.Scan(&sp.ID, &sp.Code, &sp.Name); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		species = append(species, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return species, nil
+}
+
+// categoryError переводит ошибки ограничений таблицы categories в ошибки storage.
+func categoryError(err error) error {
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return storage.ErrCategoryExists
+	case pgForeignKeyViolation:
+		return storage.ErrCategoryNotFound // несуществующий parent_id
+	}
+	return err
+}
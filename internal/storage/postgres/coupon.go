@@ -0,0 +1,242 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const couponColumns = `id, code, kind, COALESCE(percent_off, 0), COALESCE(amount_off, 0),
+	free_product_id, free_variant_id, free_quantity, min_order, starts_at, expires_at,
+	max_uses, max_uses_per_customer, active, created_at`
+
+func scanCoupon(row scanner, c *models.Coupon) error {
+	return row.Scan(&c.ID, &c.Code, &c.Kind, &c.PercentOff, &c.AmountOff,
+		&c.FreeProductID, &c.FreeVariantID, &c.FreeQuantity, &c.MinOrder, &c.StartsAt, &c.ExpiresAt,
+		&c.MaxUses, &c.MaxUsesPerCustomer, &c.Active, &c.CreatedAt)
+}
+
+// couponArgs — значения колонок купона в порядке couponColumns без id и created_at.
+func couponArgs(c models.Coupon) []any {
+	var percentOff *int
+	var amountOff *models.Money
+	switch c.Kind {
+	case models.CouponPercent:
+		percentOff = &c.PercentOff
+	case models.CouponFixed:
+		amountOff = &c.AmountOff
+	}
+	return []any{c.Code, c.Kind, percentOff, amountOff,
+		c.FreeProductID, c.FreeVariantID, c.FreeQuantity, c.MinOrder, c.StartsAt, c.ExpiresAt,
+		c.MaxUses, c.MaxUsesPerCustomer, c.Active}
+}
+
+func (s *Storage) GetAllCoupons() ([]models.Coupon, error) {
+	const fn = "storage.postgres.coupon.GetAllCoupons"
+
+	rows, err := s.db.Query(context.Background(), `SELECT `+couponColumns+` FROM coupons ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	coupons := []models.Coupon{}
+	for rows.Next() {
+		var c models.Coupon
+		if err := scanCoupon(rows, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		coupons = append(coupons, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return coupons, nil
+}
+
+func (s *Storage) GetCouponByID(id int) (models.Coupon, error) {
+	const fn = "storage.postgres.coupon.GetCouponByID"
+
+	var c models.Coupon
+	err := scanCoupon(s.db.QueryRow(context.Background(),
+		`SELECT `+couponColumns+` FROM coupons WHERE id = $1`, id), &c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, fmt.Errorf("%s: %w", fn, storage.ErrCouponNotFound)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return c, nil
+}
+
+func (s *Storage) CreateCoupon(c models.Coupon) (int, error) {
+	const fn = "storage.postgres.coupon.CreateCoupon"
+
+	var id int
+	err := s.db.QueryRow(context.Background(), `
+		INSERT INTO coupons (code, kind, percent_off, amount_off,
+			free_product_id, free_variant_id, free_quantity, min_order, starts_at, expires_at,
+			max_uses, max_uses_per_customer, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`, couponArgs(c)...).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, couponError(err))
+	}
+
+	return id, nil
+}
+
+func (s *Storage) UpdateCoupon(c models.Coupon) error {
+	const fn = "storage.postgres.coupon.UpdateCoupon"
+
+	tag, err := s.db.Exec(context.Background(), `
+		UPDATE coupons
+		SET code = $1, kind = $2, percent_off = $3, amount_off = $4,
+		    free_product_id = $5, free_variant_id = $6, free_quantity = $7, min_order = $8,
+		    starts_at = $9, expires_at = $10, max_uses = $11, max_uses_per_customer = $12, active = $13
+		WHERE id = $14`, append(couponArgs(c), c.ID)...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, couponError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrCouponNotFound)
+	}
+
+	return nil
+}
+
+// DeleteCoupon удаляет купон. Уже применённые скидки остаются в заказах
+// со своим кодом и описанием.
+func (s *Storage) DeleteCoupon(id int) error {
+	const fn = "storage.postgres.coupon.DeleteCoupon"
+
+	tag, err := s.db.Exec(context.Background(), `DELETE FROM coupons WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrCouponNotFound)
+	}
+
+	return nil
+}
+
+// couponError переводит нарушения ограничений coupons в ошибки storage.
+func couponError(err error) error {
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return storage.ErrCouponExists
+	case pgForeignKeyViolation:
+		return storage.ErrProductNotFound // несуществующий подарочный товар
+	}
+	return err
+}
+
+func (s *Storage) GetOrderDiscounts(orderID int) ([]models.OrderDiscount, error) {
+	const fn = "storage.postgres.coupon.GetOrderDiscounts"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT id, order_id, coupon_id, code, description, amount, created_at
+		FROM order_discounts
+		WHERE order_id = $1
+		ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	discounts := []models.OrderDiscount{}
+	for rows.Next() {
+		var d models.OrderDiscount
+		if err := rows.Scan(&d.ID, &d.OrderID, &d.CouponID, &d.Code, &d.Description, &d.Amount, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		discounts = append(discounts, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return discounts, nil
+}
+
+// applyCoupon проверяет промокод и применяет его к заказу orderID с суммой
+// позиций subtotal внутри транзакции оформления. Для free_item подарок
+// добавляется в заказ с резервом остатка. Возвращает сумму скидки.
+//
+// Строка купона блокируется до конца транзакции, поэтому параллельные
+// заказы не превысят лимиты использований.
+func applyCoupon(ctx context.Context, tx pgx.Tx, orderID, userID int, code string, subtotal models.Money, ttl time.Duration) (models.Money, error) {
+	var c models.Coupon
+	err := scanCoupon(tx.QueryRow(ctx, `
+		SELECT `+couponColumns+` FROM coupons
+		WHERE UPPER(code) = $1
+		FOR UPDATE`, models.NormalizeCouponCode(code)), &c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrCouponNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Даты сравниваются в базе: колонки TIMESTAMP хранят её локальное время.
+	var notStarted, expired bool
+	var uses, customerUses int
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(c.starts_at > LOCALTIMESTAMP, FALSE),
+		       COALESCE(c.expires_at <= LOCALTIMESTAMP, FALSE),
+		       (SELECT COUNT(*) FROM order_discounts d JOIN orders o ON o.id = d.order_id
+		        WHERE d.coupon_id = c.id AND o.status <> $3),
+		       (SELECT COUNT(*) FROM order_discounts d JOIN orders o ON o.id = d.order_id
+		        WHERE d.coupon_id = c.id AND o.status <> $3 AND o.user_id = $2)
+		FROM coupons c WHERE c.id = $1`,
+		c.ID, userID, models.OrderCancelled).Scan(&notStarted, &expired, &uses, &customerUses)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case !c.Active:
+		return 0, fmt.Errorf("%w: coupon is not active", storage.ErrCouponNotApplicable)
+	case notStarted:
+		return 0, fmt.Errorf("%w: coupon is not valid yet", storage.ErrCouponNotApplicable)
+	case expired:
+		return 0, fmt.Errorf("%w: coupon has expired", storage.ErrCouponNotApplicable)
+	case c.MaxUses != nil && uses >= *c.MaxUses:
+		return 0, fmt.Errorf("%w: coupon usage limit reached", storage.ErrCouponNotApplicable)
+	case c.MaxUsesPerCustomer != nil && customerUses >= *c.MaxUsesPerCustomer:
+		return 0, fmt.Errorf("%w: coupon already used", storage.ErrCouponNotApplicable)
+	case subtotal < c.MinOrder:
+		return 0, fmt.Errorf("%w: order total must be at least %s", storage.ErrCouponNotApplicable, c.MinOrder)
+	}
+
+	discount := c.Discount(subtotal)
+	if c.Kind == models.CouponFreeItem {
+		gift := models.OrderItem{ProductID: *c.FreeProductID, VariantID: c.FreeVariantID, Quantity: c.FreeQuantity}
+		price, err := addOrderItem(ctx, tx, orderID, gift, ttl)
+		if err != nil {
+			return 0, err
+		}
+		discount = price.Mul(gift.Quantity)
+	}
+	if discount <= 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO order_discounts (order_id, coupon_id, code, description, amount)
+		VALUES ($1, $2, $3, $4, $5)`,
+		orderID, c.ID, c.Code, c.Description(), discount)
+	if err != nil {
+		return 0, err
+	}
+
+	return discount, nil
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"go-pet-shop/models"
+)
+
+const (
+	// Через это время незавершённый ключ считается брошенным (процесс упал
+	// посреди запроса), и его может занять повторный запрос.
+	idempotencyLockTimeout = "1 minute"
+	// Столько хранится результат запроса.
+	idempotencyTTL = "24 hours"
+)
+
+// BeginIdempotent атомарно занимает ключ. created == true означает, что
+// запрос выполняется впервые и его результат нужно сохранить через
+// CompleteIdempotent. Иначе возвращается уже существующая запись: готовый
+// ответ или признак того, что такой же запрос выполняется прямо сейчас.
+func (s *Storage) BeginIdempotent(userID int, key, requestHash string) (models.IdempotencyRecord, bool, error) {
+	const fn = "storage.postgres.idempotency.BeginIdempotent"
+
+	ctx := context.Background()
+	rec := models.IdempotencyRecord{Key: key, RequestHash: requestHash}
+
+	// ON CONFLICT ... WHERE перезаписывает только брошенные и устаревшие ключи;
+	// при живом конфликте RETURNING ничего не вернёт.
+	tag, err := s.db.Exec(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+		    status_code = NULL, content_type = '', response_body = NULL,
+		    created_at = NOW(), completed_at = NULL
+		WHERE (idempotency_keys.status_code IS NULL
+		       AND idempotency_keys.created_at < NOW() - $4::text::interval)
+		   OR idempotency_keys.created_at < NOW() - $5::text::interval`,
+		userID, key, requestHash, idempotencyLockTimeout, idempotencyTTL)
+	if err != nil {
+		return rec, false, fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 1 {
+		return rec, true, nil
+	}
+
+	var status *int
+	err = s.db.QueryRow(ctx, `
+		SELECT request_hash, status_code, content_type, COALESCE(response_body, '')
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2`,
+		userID, key).Scan(&rec.RequestHash, &status, &rec.ContentType, &rec.Body)
+	if err != nil {
+		return rec, false, fmt.Errorf("%s: %w", fn, err)
+	}
+	if status != nil {
+		rec.Completed = true
+		rec.StatusCode = *status
+	}
+
+	return rec, false, nil
+}
+
+func (s *Storage) CompleteIdempotent(userID int, key string, statusCode int, contentType string, body []byte) error {
+	const fn = "storage.postgres.idempotency.CompleteIdempotent"
+
+	_, err := s.db.Exec(context.Background(), `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5, completed_at = NOW()
+		WHERE user_id = $1 AND key = $2`,
+		userID, key, statusCode, contentType, body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// AbortIdempotent освобождает ключ, чтобы запрос можно было повторить.
+func (s *Storage) AbortIdempotent(userID int, key string) error {
+	const fn = "storage.postgres.idempotency.AbortIdempotent"
+
+	_, err := s.db.Exec(context.Background(),
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`,
+		userID, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
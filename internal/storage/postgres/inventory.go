@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recordMovement пишет движение остатка в журнал и заполняет его ID
+// и время. Нулевое движение не записывается.
+func recordMovement(ctx context.Context, q querier, m *models.InventoryMovement) error {
+	if m.Delta == 0 {
+		return nil
+	}
+
+	return q.QueryRow(ctx, `
+		INSERT INTO inventory_movements (product_id, variant_id, delta, reason, reference_id, actor_id, comment)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7)
+		RETURNING id, created_at`,
+		m.ProductID, m.VariantID, m.Delta, m.Reason, m.ReferenceID, actorOrZero(m.ActorID), m.Comment).
+		Scan(&m.ID, &m.CreatedAt)
+}
+
+func actorOrZero(actorID *int) int {
+	if actorID == nil {
+		return 0
+	}
+	return *actorID
+}
+
+// GetInventoryMovements возвращает журнал движений товара и всех его
+// вариантов, новые записи первыми.
+func (s *Storage) GetInventoryMovements(productID int) ([]models.InventoryMovement, error) {
+	const fn = "storage.postgres.inventory.GetInventoryMovements"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT id, product_id, variant_id, delta, reason, reference_id, actor_id, comment, created_at
+		FROM inventory_movements
+		WHERE product_id = $1
+		ORDER BY id DESC`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	movements := []models.InventoryMovement{}
+	for rows.Next() {
+		var m models.InventoryMovement
+		err := rows.Scan(&m.ID, &m.ProductID, &m.VariantID, &m.Delta, &m.Reason,
+			&m.ReferenceID, &m.ActorID, &m.Comment, &m.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return movements, nil
+}
+
+// AdjustInventory меняет остаток товара (или варианта) на m.Delta и пишет
+// движение в журнал. Остаток не может стать отрицательным.
+func (s *Storage) AdjustInventory(m models.InventoryMovement) (models.InventoryMovement, error) {
+	const fn = "storage.postgres.inventory.AdjustInventory"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return m, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var tag pgconn.CommandTag
+	if m.VariantID == nil {
+		tag, err = tx.Exec(ctx, `
+			UPDATE products SET stock = stock + $1
+			WHERE id = $2 AND stock + $1 >= 0`, m.Delta, m.ProductID)
+	} else {
+		tag, err = tx.Exec(ctx, `
+			UPDATE product_variants SET stock = stock + $1
+			WHERE id = $2 AND product_id = $3 AND stock + $1 >= 0`, m.Delta, *m.VariantID, m.ProductID)
+	}
+	if err != nil {
+		return m, fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return m, fmt.Errorf("%s: %w", fn, adjustmentError(ctx, tx, m))
+	}
+
+	if err := recordMovement(ctx, tx, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return m, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return m, nil
+}
+
+// adjustmentError объясняет, почему AdjustInventory не обновил строку:
+// товара (варианта) нет или остатка не хватает.
+func adjustmentError(ctx context.Context, tx pgx.Tx, m models.InventoryMovement) error {
+	var exists bool
+	var err error
+	if m.VariantID == nil {
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, m.ProductID).Scan(&exists)
+		if err == nil && !exists {
+			return storage.ErrProductNotFound
+		}
+	} else {
+		err = tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM product_variants WHERE id = $1 AND product_id = $2)`,
+			*m.VariantID, m.ProductID).Scan(&exists)
+		if err == nil && !exists {
+			return storage.ErrVariantNotFound
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return storage.ErrOutOfStock
+}
+
+// ReconcileInventory сравнивает остатки товаров и вариантов с суммой
+// движений в журнале и возвращает расхождения.
+func (s *Storage) ReconcileInventory() ([]models.InventoryDiscrepancy, error) {
+	const fn = "storage.postgres.inventory.ReconcileInventory"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT product_id, variant_id, stock, ledger_sum FROM (
+			SELECT p.id AS product_id, NULL::int AS variant_id, p.stock,
+			       COALESCE((SELECT SUM(m.delta) FROM inventory_movements m
+			                 WHERE m.product_id = p.id AND m.variant_id IS NULL), 0) AS ledger_sum
+			FROM products p
+			UNION ALL
+			SELECT v.product_id, v.id, v.stock,
+			       COALESCE((SELECT SUM(m.delta) FROM inventory_movements m
+			                 WHERE m.variant_id = v.id), 0)
+			FROM product_variants v
+		) t
+		WHERE stock <> ledger_sum
+		ORDER BY product_id, variant_id NULLS FIRST`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	discrepancies := []models.InventoryDiscrepancy{}
+	for rows.Next() {
+		var d models.InventoryDiscrepancy
+		if err := rows.Scan(&d.ProductID, &d.VariantID, &d.Stock, &d.LedgerSum); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		discrepancies = append(discrepancies, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return discrepancies, nil
+}
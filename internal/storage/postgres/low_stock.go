@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"go-pet-shop/models"
+)
+
+// GetLowStockProducts возвращает товары с заданным порогом дозаказа,
+// у которых остаток ниже порога, вместе с отметкой об уже отправленном
+// оповещении.
+func (s *Storage) GetLowStockProducts() ([]models.LowStockProduct, error) {
+	const fn = "storage.postgres.low_stock.GetLowStockProducts"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT p.id, p.name, p.stock, p.reorder_threshold, a.alerted_at
+		FROM products p
+		LEFT JOIN low_stock_alerts a ON a.product_id = p.id
+		WHERE p.reorder_threshold > 0 AND p.stock < p.reorder_threshold
+		ORDER BY p.stock - p.reorder_threshold, p.id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	products := []models.LowStockProduct{}
+	for rows.Next() {
+		var p models.LowStockProduct
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.Stock, &p.ReorderThreshold, &p.AlertedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return products, nil
+}
+
+// MarkLowStockAlerted запоминает, что по товару отправлено оповещение.
+func (s *Storage) MarkLowStockAlerted(p models.LowStockProduct) error {
+	const fn = "storage.postgres.low_stock.MarkLowStockAlerted"
+
+	_, err := s.db.Exec(context.Background(), `
+		INSERT INTO low_stock_alerts (product_id, stock, threshold)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id) DO NOTHING`,
+		p.ProductID, p.Stock, p.ReorderThreshold)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// ClearRestockedAlerts снимает отметки об оповещениях с товаров, остаток
+// которых снова не ниже порога (или порог убран), чтобы при следующем
+// падении остатка оповещение ушло заново.
+func (s *Storage) ClearRestockedAlerts() (int64, error) {
+	const fn = "storage.postgres.low_stock.ClearRestockedAlerts"
+
+	tag, err := s.db.Exec(context.Background(), `
+		DELETE FROM low_stock_alerts a
+		USING products p
+		WHERE p.id = a.product_id
+		  AND (p.reorder_threshold = 0 OR p.stock >= p.reorder_threshold)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,253 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier — общее у pgxpool.Pool и pgx.Tx, чтобы хелперы работали
+// и внутри транзакции, и без неё.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertOrder создаёт пустой заказ в статусе pending_payment и первую запись
+// в истории статусов.
+func insertOrder(ctx context.Context, q querier, userID int) (int, error) {
+	var id int
+	err := q.QueryRow(ctx, `
+		WITH o AS (
+			INSERT INTO orders (user_id, total_price, status) VALUES ($1, 0, $2)
+			RETURNING id, status
+		)
+		INSERT INTO order_status_history (order_id, to_status)
+		SELECT id, status FROM o
+		RETURNING order_id`,
+		userID, models.OrderPendingPayment).Scan(&id)
+	return id, err
+}
+
+// transitionOrder переводит заблокированный заказ в новый статус внутри
+// транзакции tx. Допустимость перехода проверяет models.OrderStatus.
+// Заказ, уходящий из pending_payment не в отмену, списывает свои резервы
+// со склада в той же транзакции.
+func transitionOrder(ctx context.Context, tx pgx.Tx, orderID int, to models.OrderStatus, actorID *int, comment string) (models.OrderStatus, error) {
+	var from models.OrderStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&from)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", storage.ErrOrderNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := from.CheckTransition(to); err != nil {
+		return from, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE orders SET status = $1 WHERE id = $2`, to, orderID); err != nil {
+		return from, err
+	}
+
+	if from == models.OrderPendingPayment && to != models.OrderCancelled {
+		if err := commitReservations(ctx, tx, orderID); err != nil {
+			return from, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, comment)
+		VALUES ($1, $2, $3, $4, $5)`,
+		orderID, from, to, actorID, comment)
+
+	return from, err
+}
+
+// TransitionOrder меняет статус заказа вручную. В paid заказ переводит
+// только списанная оплата (UpdatePayment), отмена и возврат идут через
+// CancelOrder и RefundOrder, чтобы вернуть товар на склад.
+func (s *Storage) TransitionOrder(orderID int, to models.OrderStatus, actorID int, comment string) error {
+	const fn = "storage.postgres.order_status.TransitionOrder"
+
+	switch to {
+	case models.OrderPaid:
+		return fmt.Errorf("%s: %w: order becomes paid only by a captured payment", fn, models.ErrInvalidTransition)
+	case models.OrderCancelled, models.OrderRefunded:
+		return fmt.Errorf("%s: %w: use CancelOrder or RefundOrder", fn, models.ErrInvalidTransition)
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := transitionOrder(ctx, tx, orderID, to, &actorID, comment); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetOrderStatusHistory(orderID int) ([]models.OrderStatusChange, error) {
+	const fn = "storage.postgres.order_status.GetOrderStatusHistory"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT id, order_id, from_status, to_status, changed_by, comment, created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY created_at, id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	history := []models.OrderStatusChange{}
+	for rows.Next() {
+		var c models.OrderStatusChange
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.From, &c.To, &c.ChangedBy, &c.Comment, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return history, nil
+}
+
+// CancelOrder отменяет заказ в одной транзакции: снимает резервы остатков
+// и переводит заказ в cancelled. Деньги возвращает payment.Processor.Release,
+// сторнирующая запись появляется только после успешного возврата. Повторная отмена ничего не меняет и возвращает
+// alreadyCancelled = true. Отгруженный заказ отменить нельзя —
+// это запрещает сам переход статуса.
+func (s *Storage) CancelOrder(orderID, actorID int) (alreadyCancelled bool, err error) {
+	const fn = "storage.postgres.order_status.CancelOrder"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	alreadyCancelled, err = cancelOrder(ctx, tx, orderID, &actorID, "cancelled")
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return alreadyCancelled, nil
+}
+
+// cancelOrder — тело CancelOrder внутри уже открытой транзакции.
+// actorID == nil означает отмену системой, например после отказа в оплате.
+func cancelOrder(ctx context.Context, tx pgx.Tx, orderID int, actorID *int, comment string) (bool, error) {
+	// Блокировка строки заказа сериализует параллельные отмены.
+	var status models.OrderStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, storage.ErrOrderNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if status == models.OrderCancelled {
+		return true, nil
+	}
+	if err := status.CheckTransition(models.OrderCancelled); err != nil {
+		return false, err
+	}
+
+	if err := releaseReservations(ctx, tx, orderID, models.InventoryCancellation, actorID); err != nil {
+		return false, err
+	}
+
+	// Платежи до появления шлюза (completed) вернуть через шлюз нельзя,
+	// их сторнируем сразу. Платежи шлюза сторнирует UpdatePayment, когда
+	// возврат подтверждён.
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (order_id, amount, status)
+		SELECT $1, -SUM(amount), $2
+		FROM transactions
+		WHERE order_id = $1 AND status IN ('completed', $2)
+		HAVING SUM(amount) <> 0`,
+		orderID, models.PaymentReversed)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := transitionOrder(ctx, tx, orderID, models.OrderCancelled, actorID, comment); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// RefundOrder переводит оплаченный или доставленный заказ в refunded и в той
+// же транзакции возвращает товар на склад: неотгруженный — как отмену
+// продажи, доставленный — движением return. Повторный вызов ничего не
+// меняет и возвращает alreadyRefunded = true. Деньги возвращает
+// payment.Processor.Release.
+func (s *Storage) RefundOrder(orderID, actorID int, comment string) (alreadyRefunded bool, err error) {
+	const fn = "storage.postgres.order_status.RefundOrder"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status models.OrderStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("%s: %w", fn, storage.ErrOrderNotFound)
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if status == models.OrderRefunded {
+		return true, nil
+	}
+	if err := status.CheckTransition(models.OrderRefunded); err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	reason := models.InventoryCancellation
+	if status == models.OrderDelivered {
+		reason = models.InventoryReturn
+	}
+	if err := releaseReservations(ctx, tx, orderID, reason, &actorID); err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if _, err := transitionOrder(ctx, tx, orderID, models.OrderRefunded, &actorID, comment); err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const paymentColumns = `id, order_id, amount, status, COALESCE(gateway_reference, ''), created_at`
+
+func scanPayment(row scanner, p *models.Payment) error {
+	return row.Scan(&p.ID, &p.OrderID, &p.Amount, &p.Status, &p.GatewayReference, &p.CreatedAt)
+}
+
+// insertPayment создаёт строку transactions для оплаты заказа и первое событие по ней.
+func insertPayment(ctx context.Context, q querier, orderID int, amount models.Money) error {
+	_, err := q.Exec(ctx, `
+		WITH t AS (
+			INSERT INTO transactions (order_id, amount, status) VALUES ($1, $2, $3)
+			RETURNING id, status
+		)
+		INSERT INTO transaction_events (transaction_id, status)
+		SELECT id, status FROM t`,
+		orderID, amount, models.PaymentPending)
+	return err
+}
+
+// GetOrderPayment возвращает платёж, которым оплачивается заказ
+// (сторнирующие записи не учитываются).
+func (s *Storage) GetOrderPayment(orderID int) (models.Payment, error) {
+	const fn = "storage.postgres.payment.GetOrderPayment"
+
+	var p models.Payment
+	err := scanPayment(s.db.QueryRow(context.Background(), `
+		SELECT `+paymentColumns+`
+		FROM transactions
+		WHERE order_id = $1 AND status <> $2
+		ORDER BY id
+		LIMIT 1`, orderID, models.PaymentReversed), &p)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, fmt.Errorf("%s: %w", fn, storage.ErrPaymentNotFound)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return p, nil
+}
+
+func (s *Storage) GetPaymentByReference(ref string) (models.Payment, error) {
+	const fn = "storage.postgres.payment.GetPaymentByReference"
+
+	var p models.Payment
+	err := scanPayment(s.db.QueryRow(context.Background(),
+		`SELECT `+paymentColumns+` FROM transactions WHERE gateway_reference = $1`, ref), &p)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, fmt.Errorf("%s: %w", fn, storage.ErrPaymentNotFound)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return p, nil
+}
+
+// UpdatePayment меняет статус платежа, записывает событие в transaction_events
+// и в той же транзакции применяет последствия к заказу: списанная оплата
+// переводит заказ в paid, отказ или сбой шлюза отменяют его с возвратом остатков,
+// подтверждённый возврат добавляет сторнирующую запись.
+func (s *Storage) UpdatePayment(paymentID int, status models.PaymentStatus, ref, detail string) (models.Payment, error) {
+	const fn = "storage.postgres.payment.UpdatePayment"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Payment{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var p models.Payment
+	err = scanPayment(tx.QueryRow(ctx, `
+		UPDATE transactions
+		SET status = $1,
+		    gateway_reference = COALESCE(NULLIF($2, ''), gateway_reference),
+		    updated_at = NOW()
+		WHERE id = $3
+		RETURNING `+paymentColumns, status, ref, paymentID), &p)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, fmt.Errorf("%s: %w", fn, storage.ErrPaymentNotFound)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transaction_events (transaction_id, status, gateway_reference, detail)
+		VALUES ($1, $2, NULLIF($3, ''), $4)`,
+		p.ID, status, p.GatewayReference, detail)
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	switch status {
+	case models.PaymentCaptured:
+		// Если заказ успели отменить, статус не трогаем: деньги вернёт payment.Processor.
+		var orderStatus models.OrderStatus
+		err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, p.OrderID).Scan(&orderStatus)
+		if err == nil && orderStatus == models.OrderPendingPayment {
+			_, err = transitionOrder(ctx, tx, p.OrderID, models.OrderPaid, nil, "payment captured")
+		}
+	case models.PaymentDeclined, models.PaymentFailed:
+		_, err = cancelOrder(ctx, tx, p.OrderID, nil, "payment "+string(status))
+	case models.PaymentRefunded:
+		err = reversePayment(ctx, tx, p)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return p, nil
+}
+
+// reversePayment добавляет сторнирующую запись на сумму возвращённого платежа.
+// Повторный вызов для того же заказа ничего не добавляет.
+func reversePayment(ctx context.Context, tx pgx.Tx, p models.Payment) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO transactions (order_id, amount, status)
+		SELECT $1, -$2::numeric, $3
+		WHERE NOT EXISTS (SELECT 1 FROM transactions WHERE order_id = $1 AND status = $3)`,
+		p.OrderID, p.Amount, models.PaymentReversed)
+	return err
+}
+
+// GetUnreleasedPayments возвращает отменённые и возвращённые заказы, оплата
+// которых всё ещё заблокирована или списана: возврат через шлюз не удался.
+// Заказы, статус которых менялся позже чем olderThan назад, пропускаются,
+// чтобы не повторять возврат, который ещё выполняется.
+func (s *Storage) GetUnreleasedPayments(olderThan time.Duration, limit int) ([]int, error) {
+	const fn = "storage.postgres.payment.GetUnreleasedPayments"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT DISTINCT o.id
+		FROM orders o
+		JOIN transactions t ON t.order_id = o.id
+		WHERE o.status IN ($1, $2) AND t.status IN ($3, $4)
+		  AND NOT EXISTS (
+			SELECT 1 FROM order_status_history h
+			WHERE h.order_id = o.id AND h.created_at > NOW() - $5::bigint * INTERVAL '1 second'
+		  )
+		ORDER BY o.id
+		LIMIT $6`,
+		models.OrderCancelled, models.OrderRefunded, models.PaymentAuthorized, models.PaymentCaptured,
+		int64(olderThan.Seconds()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	orderIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return orderIDs, nil
+}
@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const petColumns = `p.id, p.user_id, p.name, sp.code, p.breed, to_char(p.birth_date, 'YYYY-MM-DD'),
+	p.weight_grams, p.allergies, p.created_at`
+
+const petFrom = ` FROM pets p JOIN species sp ON sp.id = p.species_id`
+
+func scanPet(row scanner, p *models.Pet) error {
+	return row.Scan(&p.ID, &p.UserID, &p.Name, &p.Species, &p.Breed, &p.BirthDate,
+		&p.WeightGrams, &p.Allergies, &p.CreatedAt)
+}
+
+// speciesID находит вид животного по коду.
+func speciesID(ctx context.Context, q querier, code models.Species) (int, error) {
+	var id int
+	err := q.QueryRow(ctx, `SELECT id FROM species WHERE code = $1`, code).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrUnknownSpecies
+	}
+	return id, err
+}
+
+// GetPets возвращает питомцев пользователя.
+func (s *Storage) GetPets(email string) ([]models.Pet, error) {
+	const fn = "storage.postgres.pet.GetPets"
+
+	ctx := context.Background()
+	userID, err := userIDByEmail(ctx, s.db, email)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	rows, err := s.db.Query(ctx, `SELECT `+petColumns+petFrom+` WHERE p.user_id = $1 ORDER BY p.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	pets := []models.Pet{}
+	for rows.Next() {
+		var p models.Pet
+		if err := scanPet(rows, &p); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		pets = append(pets, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return pets, nil
+}
+
+func (s *Storage) GetPetByID(id int) (models.Pet, error) {
+	const fn = "storage.postgres.pet.GetPetByID"
+
+	var p models.Pet
+	err := scanPet(s.db.QueryRow(context.Background(), `SELECT `+petColumns+petFrom+` WHERE p.id = $1`, id), &p)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, fmt.Errorf("%s: %w", fn, storage.ErrPetNotFound)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return p, nil
+}
+
+func (s *Storage) CreatePet(email string, p models.Pet) (models.Pet, error) {
+	const fn = "storage.postgres.pet.CreatePet"
+
+	ctx := context.Background()
+	userID, err := userIDByEmail(ctx, s.db, email)
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+	species, err := speciesID(ctx, s.db, p.Species)
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var id int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO pets (user_id, name, species_id, breed, birth_date, weight_grams, allergies)
+		VALUES ($1, $2, $3, $4, $5::date, $6, $7)
+		RETURNING id`,
+		userID, p.Name, species, p.Breed, p.BirthDate, p.WeightGrams, p.Allergies).Scan(&id)
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return s.GetPetByID(id)
+}
+
+// UpdatePet заменяет данные питомца p.ID пользователя email.
+func (s *Storage) UpdatePet(email string, p models.Pet) (models.Pet, error) {
+	const fn = "storage.postgres.pet.UpdatePet"
+
+	ctx := context.Background()
+	species, err := speciesID(ctx, s.db, p.Species)
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	tag, err := s.db.Exec(ctx, `
+		UPDATE pets p
+		SET name = $3, species_id = $4, breed = $5, birth_date = $6::date, weight_grams = $7, allergies = $8
+		FROM users u
+		WHERE p.id = $1 AND p.user_id = u.id AND u.email = $2`,
+		p.ID, email, p.Name, species, p.Breed, p.BirthDate, p.WeightGrams, p.Allergies)
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return p, fmt.Errorf("%s: %w", fn, storage.ErrPetNotFound)
+	}
+
+	return s.GetPetByID(p.ID)
+}
+
+// DeletePet удаляет питомца. В заказах ссылка на него обнуляется.
+func (s *Storage) DeletePet(email string, id int) error {
+	const fn = "storage.postgres.pet.DeletePet"
+
+	tag, err := s.db.Exec(context.Background(), `
+		DELETE FROM pets p
+		USING users u
+		WHERE p.id = $1 AND p.user_id = u.id AND u.email = $2`, id, email)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrPetNotFound)
+	}
+
+	return nil
+}
+
+// assignOrderPet отмечает, для какого питомца покупателя userID оформлен заказ.
+func assignOrderPet(ctx context.Context, tx pgx.Tx, orderID, userID, petID int) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE orders SET pet_id = $3
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM pets WHERE id = $3 AND user_id = $2)`,
+		orderID, userID, petID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrPetNotFound
+	}
+	return nil
+}
@@ -6,16 +6,22 @@ import (
 	"fmt"
 	"go-pet-shop/internal/storage"
 	"go-pet-shop/models"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Storage struct {
 	db *pgxpool.Pool
+	// reservationTTL — сколько остаток удерживается за неоплаченным заказом.
+	reservationTTL time.Duration
+	// taxRegion — регион налогов для заказов, где он не указан.
+	taxRegion string
 }
 
 // PlaceOrder implements handlers.Orders.
-func (s *Storage) PlaceOrder(userEmail string, items []models.OrderItem) (int, error) {
+func (s *Storage) PlaceOrder(userEmail string, draft models.OrderDraft) (int, error) {
 	ctx := context.Background()
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
@@ -30,52 +36,128 @@ func (s *Storage) PlaceOrder(userEmail string, items []models.OrderItem) (int, e
 		return 0, fmt.Errorf("user not found: %w", err)
 	}
 
+	if draft.TaxRegion == "" {
+		draft.TaxRegion = s.taxRegion
+	}
+	orderID, err := placeOrder(ctx, tx, userID, draft, s.reservationTTL)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return orderID, nil
+}
+
+// placeOrder создаёт заказ с позициями, резервирует остатки на ttl, применяет
+// промокод, считает налог региона draft.TaxRegion и доставку по адресу
+// draft.AddressID и заводит платёж внутри транзакции tx. Общая часть
+// PlaceOrder и CheckoutCart.
+func placeOrder(ctx context.Context, tx pgx.Tx, userID int, draft models.OrderDraft, ttl time.Duration) (int, error) {
+	if len(draft.Items) == 0 {
+		return 0, storage.ErrEmptyOrder
+	}
+
 	// Создать заказ
-	var orderID int
-	err = tx.QueryRow(ctx, `INSERT INTO orders (user_id, total_price) VALUES ($1, 0) RETURNING id`, userID).Scan(&orderID)
+	orderID, err := insertOrder(ctx, tx, userID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create order: %w", err)
 	}
 
+	if draft.PetID != nil {
+		if err := assignOrderPet(ctx, tx, orderID, userID, *draft.PetID); err != nil {
+			return 0, fmt.Errorf("failed to assign pet: %w", err)
+		}
+	}
+
 	// 3Добавить товары
-	var total float64
-	for _, item := range items {
-		var price float64
-		err = tx.QueryRow(ctx, `SELECT price FROM products WHERE id = $1`, item.ProductID).Scan(&price)
+	var subtotal models.Money
+	for _, item := range draft.Items {
+		if item.Quantity <= 0 {
+			return 0, fmt.Errorf("invalid quantity for product %d", item.ProductID)
+		}
+
+		price, err := addOrderItem(ctx, tx, orderID, item, ttl)
 		if err != nil {
-			return 0, fmt.Errorf("product not found: %w", err)
+			return 0, err
 		}
 
-		_, err = tx.Exec(ctx, `INSERT INTO order_items (order_id, product_id, quantity) VALUES ($1, $2, $3)`,
-			orderID, item.ProductID, item.Quantity)
+		subtotal += price.Mul(item.Quantity)
+	}
+
+	// Применить промокод. Подарок по free_item попадает в позиции заказа,
+	// поэтому итог считается по order_items, а не по subtotal.
+	var discount models.Money
+	if draft.CouponCode != "" {
+		discount, err = applyCoupon(ctx, tx, orderID, userID, draft.CouponCode, subtotal, ttl)
 		if err != nil {
-			return 0, fmt.Errorf("failed to insert order item: %w", err)
+			return 0, fmt.Errorf("failed to apply coupon: %w", err)
 		}
+	}
+	var itemsTotal models.Money
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(unit_price * quantity), 0)
+		FROM order_items WHERE order_id = $1`, orderID).Scan(&itemsTotal)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum order items: %w", err)
+	}
+	total := itemsTotal - discount
 
-		total += price * float64(item.Quantity)
+	// Налог: при ценах без налога начисляется сверху
+	tax, included, err := applyTax(ctx, tx, orderID, draft.TaxRegion, discount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply tax: %w", err)
+	}
+	if !included {
+		total += tax
+	}
+
+	// Доставка: отдельная строка заказа, входит в сумму платежа
+	if draft.AddressID != nil {
+		shipping, err := applyShipping(ctx, tx, orderID, userID, *draft.AddressID, draft.ShippingMethod, itemsTotal-discount)
+		if err != nil {
+			return 0, fmt.Errorf("failed to apply shipping: %w", err)
+		}
+		total += shipping
 	}
 
 	// Обновить общую сумму
-	_, err = tx.Exec(ctx, `UPDATE orders SET total_price = $1 WHERE id = $2`, total, orderID)
+	_, err = tx.Exec(ctx, `UPDATE orders SET total_price = $1, discount_total = $2 WHERE id = $3`, total, discount, orderID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to update total price: %w", err)
 	}
 
-	// Записать транзакцию
-	_, err = tx.Exec(ctx, `INSERT INTO transactions (order_id, amount, status) VALUES ($1, $2, $3)`,
-		orderID, total, "pending")
+	// Записать транзакцию: оплату проводит payment.Processor после создания заказа
+	err = insertPayment(ctx, tx, orderID, total)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if err = tx.Commit(ctx); err != nil {
-		return 0, fmt.Errorf("failed to commit: %w", err)
+	return orderID, nil
+}
+
+// addOrderItem резервирует остаток позиции и добавляет её в заказ
+// с текущей ценой. Возвращает цену за штуку.
+func addOrderItem(ctx context.Context, tx pgx.Tx, orderID int, item models.OrderItem, ttl time.Duration) (models.Money, error) {
+	price, err := reserveOrderItem(ctx, tx, orderID, item, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve order item: %w", err)
 	}
 
-	return orderID, nil
+	_, err = tx.Exec(ctx, `
+		INSERT INTO order_items (order_id, product_id, variant_id, quantity, unit_price)
+		VALUES ($1, $2, $3, $4, $5)`,
+		orderID, item.ProductID, item.VariantID, item.Quantity, price)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert order item: %w", err)
+	}
+
+	return price, nil
 }
 
-func New(databaseUrl string) (*Storage, error) {
+func New(databaseUrl string, reservationTTL time.Duration, taxRegion string) (*Storage, error) {
 	const fn = "storage.postgres.New"
 
 	db, err := pgxpool.New(context.Background(), databaseUrl)
@@ -83,7 +165,7 @@ func New(databaseUrl string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	return &Storage{db: db}, nil
+	return &Storage{db: db, reservationTTL: reservationTTL, taxRegion: taxRegion}, nil
 }
 
 func (s *Storage) Close() error {
@@ -91,25 +173,10 @@ func (s *Storage) Close() error {
 	return nil
 }
 
-func (s *Storage) CreateOrder(order models.Order) (int, error) {
-	query := `
-		INSERT INTO orders (user_id, total_price)
-		VALUES ($1, 0)
-		RETURNING id;
-	`
-
-	var id int
-	err := s.db.QueryRow(context.Background(), query, order.CustomerID).Scan(&id)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create order: %w", err)
-	}
-
-	return id, nil
-}
-
 func (s *Storage) GetOrderByID(id int) (models.Order, error) {
 	query := `
-		SELECT id, user_id, created_at
+		SELECT id, user_id, status, total_price, discount_total, tax_total, shipping_total, pet_id,
+		       COALESCE(tax_region, ''), prices_include_tax, created_at
 		FROM orders
 		WHERE id = $1;
 	`
@@ -118,6 +185,14 @@ func (s *Storage) GetOrderByID(id int) (models.Order, error) {
 	err := s.db.QueryRow(context.Background(), query, id).Scan(
 		&order.ID,
 		&order.CustomerID,
+		&order.Status,
+		&order.TotalPrice,
+		&order.DiscountTotal,
+		&order.TaxTotal,
+		&order.ShippingTotal,
+		&order.PetID,
+		&order.TaxRegion,
+		&order.PricesIncludeTax,
 		&order.CreatedAt,
 	)
 	if err != nil {
@@ -132,7 +207,8 @@ func (s *Storage) GetOrderByID(id int) (models.Order, error) {
 
 func (s *Storage) GetOrdersByUserEmail(email string) ([]models.Order, error) {
 	query := `
-		SELECT o.id, o.user_id, o.created_at
+		SELECT o.id, o.user_id, o.status, o.total_price, o.discount_total, o.tax_total, o.shipping_total, o.pet_id,
+		       COALESCE(o.tax_region, ''), o.prices_include_tax, o.created_at
 		FROM orders o
 		JOIN users u ON o.user_id = u.id
 		WHERE u.email = $1;
@@ -147,7 +223,8 @@ func (s *Storage) GetOrdersByUserEmail(email string) ([]models.Order, error) {
 	var orders []models.Order
 	for rows.Next() {
 		var o models.Order
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.CreatedAt); err != nil {
+		if err := rows.Scan(&o.ID, &o.CustomerID, &o.Status, &o.TotalPrice, &o.DiscountTotal, &o.TaxTotal, &o.ShippingTotal, &o.PetID,
+			&o.TaxRegion, &o.PricesIncludeTax, &o.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
 		orders = append(orders, o)
@@ -158,7 +235,7 @@ func (s *Storage) GetOrdersByUserEmail(email string) ([]models.Order, error) {
 
 func (s *Storage) GetOrderItemsByOrderID(orderID int) ([]models.OrderItem, error) {
 	query := `
-		SELECT id, order_id, product_id, quantity
+		SELECT id, order_id, product_id, variant_id, quantity, unit_price
 		FROM order_items
 		WHERE order_id = $1;
 	`
@@ -172,7 +249,7 @@ func (s *Storage) GetOrderItemsByOrderID(orderID int) ([]models.OrderItem, error
 	var items []models.OrderItem
 	for rows.Next() {
 		var item models.OrderItem
-		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity); err != nil {
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.VariantID, &item.Quantity, &item.UnitPrice); err != nil {
 			return nil, fmt.Errorf("scan order item: %w", err)
 		}
 		items = append(items, item)
@@ -181,19 +258,6 @@ func (s *Storage) GetOrderItemsByOrderID(orderID int) ([]models.OrderItem, error
 	return items, nil
 }
 
-func (s *Storage) AddOrderItem(item models.OrderItem) error {
-	query := `
-		INSERT INTO order_items (order_id, product_id, quantity)
-		VALUES ($1, $2, $3);
-	`
-
-	_, err := s.db.Exec(context.Background(), query, item.OrderID, item.ProductID, item.Quantity)
-	if err != nil {
-		return fmt.Errorf("add order item: %w", err)
-	}
-	return nil
-}
-
 func (s *Storage) GetUserOrderHistory(email string) ([]models.OrderDetail, error) {
     query := `
         SELECT 
@@ -202,14 +266,27 @@ func (s *Storage) GetUserOrderHistory(email string) ([]models.OrderDetail, error
             oi.product_id,
             p.name,
             oi.quantity,
+            COALESCE(oi.unit_price, p.price),
+            o.total_price,
+            o.discount_total,
+            o.tax_total,
+            o.prices_include_tax,
+            o.shipping_total,
+            o.pet_id,
+            o.status,
             t.status
         FROM orders o
         JOIN users u ON o.user_id = u.id
         JOIN order_items oi ON oi.order_id = o.id
         JOIN products p ON p.id = oi.product_id
-        LEFT JOIN transactions t ON t.order_id = o.id
+        LEFT JOIN LATERAL (
+            SELECT status FROM transactions
+            WHERE order_id = o.id
+            ORDER BY id DESC
+            LIMIT 1
+        ) t ON true
         WHERE u.email = $1
-        ORDER BY o.created_at DESC;
+        ORDER BY o.created_at DESC, o.id DESC, oi.id;
     `
 
     rows, err := s.db.Query(context.Background(), query, email)
@@ -221,49 +298,88 @@ func (s *Storage) GetUserOrderHistory(email string) ([]models.OrderDetail, error
     var history []models.OrderDetail
     for rows.Next() {
         var od models.OrderDetail
-        err := rows.Scan(&od.OrderID, &od.CreatedAt, &od.ProductID, &od.ProductName, &od.Quantity, &od.TransactionStatus)
+        err := rows.Scan(&od.OrderID, &od.CreatedAt, &od.ProductID, &od.ProductName, &od.Quantity, &od.Price, &od.TotalPrice, &od.Discount, &od.Tax, &od.PricesIncludeTax, &od.Shipping, &od.PetID, &od.Status, &od.TransactionStatus)
         if err != nil {
             return nil, fmt.Errorf("scan order detail: %w", err)
         }
         history = append(history, od)
     }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("iterate user order history: %w", err)
+    }
     return history, nil
 }
 
-func (s *Storage) GetPopularProducts() ([]models.PopularProduct, error) {
+// GetPopularProductsByCategory возвращает до perCategory самых продаваемых
+// товаров в каждой категории по оплаченным заказам. Товары без категории
+// идут отдельной группой.
+func (s *Storage) GetPopularProductsByCategory(perCategory int) ([]models.CategoryPopularProducts, error) {
+    const fn = "storage.postgres.GetPopularProductsByCategory"
+
     query := `
-        SELECT
-            p.id,
-            p.name,
-            SUM(oi.quantity) AS total_sold
-        FROM order_items oi
-        JOIN products p ON oi.product_id = p.id
-        GROUP BY p.id, p.name
-        ORDER BY total_sold DESC
-        LIMIT 10;
+        WITH sold AS (
+            SELECT
+                p.id,
+                p.name,
+                p.category_id,
+                SUM(oi.quantity) AS total_sold,
+                SUM(oi.quantity * COALESCE(oi.unit_price, p.price)) AS revenue
+            FROM order_items oi
+            JOIN orders o ON o.id = oi.order_id
+            JOIN products p ON oi.product_id = p.id
+            WHERE ` + paidOrder("o") + `
+            GROUP BY p.id, p.name, p.category_id
+        ), ranked AS (
+            SELECT sold.*,
+                ROW_NUMBER() OVER (PARTITION BY category_id ORDER BY total_sold DESC, id) AS rn
+            FROM sold
+        )
+        SELECT r.category_id, COALESCE(c.name, ''), r.id, r.name, r.total_sold, r.revenue, r.rn
+        FROM ranked r
+        LEFT JOIN categories c ON c.id = r.category_id
+        WHERE r.rn <= $1
+        ORDER BY c.name NULLS LAST, r.category_id, r.rn;
     `
-    rows, err := s.db.Query(context.Background(), query)
+    rows, err := s.db.Query(context.Background(), query, perCategory)
     if err != nil {
-        return nil, fmt.Errorf("query popular products: %w", err)
+        return nil, fmt.Errorf("%s: %w", fn, err)
     }
     defer rows.Close()
 
-    var popular []models.PopularProduct
+    groups := []models.CategoryPopularProducts{}
     for rows.Next() {
+        var categoryID *int
+        var categoryName string
         var p models.PopularProduct
-        if err := rows.Scan(&p.ID, &p.Name, &p.TotalSold); err != nil {
-            return nil, fmt.Errorf("scan popular product: %w", err)
+        if err := rows.Scan(&categoryID, &categoryName, &p.ID, &p.Name, &p.TotalSold, &p.Revenue, &p.Rank); err != nil {
+            return nil, fmt.Errorf("%s: %w", fn, err)
         }
-        popular = append(popular, p)
+
+        last := len(groups) - 1
+        if last < 0 || !sameCategory(groups[last].CategoryID, categoryID) {
+            groups = append(groups, models.CategoryPopularProducts{CategoryID: categoryID, CategoryName: categoryName})
+            last++
+        }
+        groups[last].Products = append(groups[last].Products, p)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", fn, err)
     }
 
-    return popular, nil
+    return groups, nil
+}
+
+func sameCategory(a, b *int) bool {
+    if a == nil || b == nil {
+        return a == b
+    }
+    return *a == *b
 }
 
 func (s *Storage) CreateUser(user models.User) error {
     _, err := s.db.Exec(context.Background(),
-        "INSERT INTO users (email, name) VALUES ($1, $2)",
-        user.Email, user.Name,
+        "INSERT INTO users (email, name, password_hash, role) VALUES ($1, $2, $3, $4)",
+        user.Email, user.Name, user.PasswordHash, user.Role,
     )
     return err
 }
@@ -271,22 +387,38 @@ func (s *Storage) CreateUser(user models.User) error {
 func (s *Storage) GetUserByEmail(email string) (models.User, error) {
     var user models.User
     err := s.db.QueryRow(context.Background(),
-        "SELECT email, name FROM users WHERE email = $1",
+        "SELECT id, email, name, role, password_hash FROM users WHERE email = $1",
         email,
-    ).Scan(&user.Email, &user.Name)
+    ).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.PasswordHash)
     return user, err
 }
 
+func (s *Storage) UpdateUserRole(email string, role models.Role) error {
+    const fn = "storage.postgres.UpdateUserRole"
+
+    tag, err := s.db.Exec(context.Background(),
+        "UPDATE users SET role = $1 WHERE email = $2",
+        role, email,
+    )
+    if err != nil {
+        return fmt.Errorf("%s: %w", fn, err)
+    }
+    if tag.RowsAffected() == 0 {
+        return fmt.Errorf("%s: %w", fn, pgx.ErrNoRows)
+    }
+    return nil
+}
+
 func (s *Storage) GetAllUsers() ([]models.User, error) {
     var users []models.User
-    rows, err := s.db.Query(context.Background(), "SELECT email, name FROM users")
+    rows, err := s.db.Query(context.Background(), "SELECT id, email, name, role FROM users")
     if err != nil {
         return nil, err
     }
     defer rows.Close()
     for rows.Next() {
         var user models.User
-        if err := rows.Scan(&user.Email, &user.Name); err != nil {
+        if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Role); err != nil {
             return nil, err
         }
         users = append(users, user)
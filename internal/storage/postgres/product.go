@@ -2,43 +2,412 @@ package postgres
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go-pet-shop/internal/storage"
 	"go-pet-shop/models"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
 )
 
-func (s *Storage) GetAllProducts() ([]models.Product, error) {
+// productColumns перечисляет колонки товара в порядке, который ожидает scanProduct.
+func productColumns(alias string) string {
+	return fmt.Sprintf(`%[1]s.id, COALESCE(%[1]s.sku, ''), %[1]s.name, %[1]s.price, %[1]s.stock, `+availableProductStock(alias)+`,
+		%[1]s.reorder_threshold, %[1]s.tax_class, %[1]s.weight_grams,
+		%[1]s.rating_avg, %[1]s.rating_count, %[1]s.pet_min_weight_grams, %[1]s.pet_max_weight_grams, %[1]s.category_id, %[1]s.created_at,
+		COALESCE((SELECT array_agg(sp.code ORDER BY sp.code)
+		          FROM product_species ps JOIN species sp ON sp.id = ps.species_id
+		          WHERE ps.product_id = %[1]s.id), '{}')`, alias)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProduct читает колонки productColumns и, если нужно, дополнительные
+// колонки после них.
+func scanProduct(row scanner, p *models.Product, extra ...any) error {
+	var species []string
+	dest := append([]any{&p.ID, &p.SKU, &p.Name, &p.Price, &p.Stock, &p.Available, &p.ReorderThreshold, &p.TaxClass, &p.WeightGrams, &p.Rating, &p.ReviewCount, &p.PetMinWeightGrams, &p.PetMaxWeightGrams, &p.CategoryID, &p.CreatedAt, &species}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+
+	p.Species = make([]models.Species, len(species))
+	for i, code := range species {
+		p.Species[i] = models.Species(code)
+	}
+	return nil
+}
+
+// productSort описывает колонку сортировки каталога. Для keyset-пагинации
+// к ней всегда добавляется id, чтобы порядок был однозначным.
+type productSort struct {
+	column string
+	cast   string // значение из курсора передаётся строкой и приводится к типу колонки
+	desc   bool
+}
+
+var productSorts = map[string]productSort{
+	"":                   {column: "id", cast: "::text::int"},
+	models.SortPriceAsc:  {column: "price", cast: "::text::numeric"},
+	models.SortPriceDesc: {column: "price", cast: "::text::numeric", desc: true},
+	models.SortNameAsc:   {column: "name"},
+	models.SortNameDesc:  {column: "name", desc: true},
+	models.SortNewest:    {column: "created_at", cast: "::text::timestamp", desc: true},
+	models.SortRating:    {column: "rating_avg", cast: "::text::numeric", desc: true},
+}
+
+// productCursor — содержимое курсора: значение колонки сортировки и id
+// последнего товара на странице.
+type productCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeProductCursor(c productCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeProductCursor(token, sort string) (productCursor, error) {
+	var c productCursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, storage.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != sort {
+		return c, storage.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func sortValue(column string, p models.Product) string {
+	switch column {
+	case "price":
+		return p.Price.String()
+	case "name":
+		return p.Name
+	case "created_at":
+		return p.CreatedAt.Format("2006-01-02 15:04:05.999999")
+	case "rating_avg":
+		return strconv.FormatFloat(p.Rating, 'f', 2, 64)
+	default:
+		return strconv.Itoa(p.ID)
+	}
+}
+
+// productFilters собирает WHERE по фильтрам запроса. Курсор в него не входит,
+// чтобы тот же набор условий можно было использовать для подсчёта total.
+func productFilters(q models.ProductQuery) ([]string, []any) {
+	var where []string
+	var args []any
+
+	if q.MinPrice != nil {
+		args = append(args, *q.MinPrice)
+		where = append(where, fmt.Sprintf("price >= $%d", len(args)))
+	}
+	if q.MaxPrice != nil {
+		args = append(args, *q.MaxPrice)
+		where = append(where, fmt.Sprintf("price <= $%d", len(args)))
+	}
+	if q.InStock {
+		where = append(where, availableProductStock("products")+" > 0")
+	}
+	if q.CategoryID != nil {
+		args = append(args, *q.CategoryID)
+		where = append(where, "category_id IN "+categorySubtree(len(args)))
+	}
+	if q.Species != "" {
+		args = append(args, q.Species)
+		where = append(where, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM product_species ps JOIN species sp ON sp.id = ps.species_id
+			WHERE ps.product_id = products.id AND sp.code = $%d)`, len(args)))
+	}
+	if q.PetSpecies != "" {
+		args = append(args, q.PetSpecies)
+		where = append(where, fmt.Sprintf(`(NOT EXISTS (SELECT 1 FROM product_species ps WHERE ps.product_id = products.id)
+			OR EXISTS (
+				SELECT 1 FROM product_species ps JOIN species sp ON sp.id = ps.species_id
+				WHERE ps.product_id = products.id AND sp.code = $%d))`, len(args)))
+	}
+	if q.PetWeightGrams != nil {
+		args = append(args, *q.PetWeightGrams)
+		where = append(where, fmt.Sprintf(`(pet_min_weight_grams IS NULL OR pet_min_weight_grams <= $%[1]d)
+			AND (pet_max_weight_grams IS NULL OR pet_max_weight_grams >= $%[1]d)`, len(args)))
+	}
+
+	return where, args
+}
+
+// categorySubtree возвращает подзапрос с id категории из параметра $param
+// и всех её подкатегорий.
+func categorySubtree(param int) string {
+	return fmt.Sprintf(`(
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $%d
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+			)
+			SELECT id FROM subtree)`, param)
+}
+
+func whereClause(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where, " AND ")
+}
+
+func (s *Storage) GetAllProducts(q models.ProductQuery) (models.ProductPage, error) {
 	const fn = "storage.postgres.product.GetAllProducts"
 
-	rows, err := s.db.Query(context.Background(), `SELECT * FROM products`)
+	var page models.ProductPage
+
+	sort, ok := productSorts[q.Sort]
+	if !ok {
+		return page, fmt.Errorf("%s: unknown sort %q", fn, q.Sort)
+	}
+
+	where, args := productFilters(q)
+
+	err := s.db.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM products`+whereClause(where), args...).Scan(&page.Total)
+	if err != nil {
+		return page, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	cmp, dir := ">", "ASC"
+	if sort.desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeProductCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return page, fmt.Errorf("%s: %w", fn, err)
+		}
+		args = append(args, c.Value, c.ID)
+		where = append(where, fmt.Sprintf("(%s, id) %s ($%d%s, $%d)",
+			sort.column, cmp, len(args)-1, sort.cast, len(args)))
+	}
+
+	// Берём на одну строку больше, чтобы понять, есть ли следующая страница.
+	args = append(args, q.Limit+1)
+	query := fmt.Sprintf(`SELECT %s FROM products%s ORDER BY %s %s, id %s LIMIT $%d`,
+		productColumns("products"), whereClause(where), sort.column, dir, dir, len(args))
+
+	rows, err := s.db.Query(context.Background(), query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", fn, err)
+		return page, fmt.Errorf("%s: %w", fn, err)
 	}
 	defer rows.Close()
 
-	var products []models.Product
+	page.Items = []models.Product{}
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock); err != nil {
-			return nil, fmt.Errorf("%s: %w", fn, err)
+		if err := scanProduct(rows, &p); err != nil {
+			return page, fmt.Errorf("%s: %w", fn, err)
 		}
-		products = append(products, p)
+		page.Items = append(page.Items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("%s: %w", fn, err)
 	}
-	return products, nil
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = encodeProductCursor(productCursor{
+			Sort:  q.Sort,
+			Value: sortValue(sort.column, last),
+			ID:    last.ID,
+		})
+	}
+
+	return page, nil
 }
 
-func (s *Storage) CreateProduct(p models.Product) error {
+// Порог word_similarity, ниже которого товар не считается опечаткой запроса.
+const fuzzyThreshold = 0.3
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+
+// SearchProducts ищет товары полнотекстовым поиском по search_vector.
+// Если точных совпадений нет, повторяет поиск по триграммам (pg_trgm),
+// чтобы находить запросы с опечатками вроде "kiten food".
+func (s *Storage) SearchProducts(q models.ProductSearchQuery) (models.ProductSearchPage, error) {
+	const fn = "storage.postgres.product.SearchProducts"
+
+	page, err := s.searchFullText(q)
+	if err != nil {
+		return page, fmt.Errorf("%s: %w", fn, err)
+	}
+	if page.Total > 0 {
+		return page, nil
+	}
+
+	page, err = s.searchFuzzy(q)
+	if err != nil {
+		return page, fmt.Errorf("%s: %w", fn, err)
+	}
+	page.Fuzzy = true
+
+	return page, nil
+}
+
+func (s *Storage) searchFullText(q models.ProductSearchQuery) (models.ProductSearchPage, error) {
+	var page models.ProductSearchPage
+
+	err := s.db.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM products
+		WHERE search_vector @@ websearch_to_tsquery('simple', $1)`,
+		q.Text).Scan(&page.Total)
+	if err != nil {
+		return page, err
+	}
+	if page.Total == 0 {
+		return page, nil
+	}
+
+	page.Items, err = s.scanSearchResults(`
+		WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query)
+		SELECT `+productColumns("p")+`,
+		       ts_rank_cd(p.search_vector, q.query) AS rank,
+		       ts_headline('simple', p.name, q.query, $2)
+		FROM products p, q
+		WHERE p.search_vector @@ q.query
+		ORDER BY rank DESC, p.id
+		LIMIT $3 OFFSET $4`,
+		q.Text, headlineOptions, q.Limit, q.Offset)
+
+	return page, err
+}
+
+func (s *Storage) searchFuzzy(q models.ProductSearchQuery) (models.ProductSearchPage, error) {
+	var page models.ProductSearchPage
+
+	err := s.db.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM products
+		WHERE word_similarity($1, name) >= $2`,
+		q.Text, fuzzyThreshold).Scan(&page.Total)
+	if err != nil {
+		return page, err
+	}
+
+	// Для подсветки используем префиксы слов запроса: "kiten" -> "kit:*".
+	page.Items, err = s.scanSearchResults(`
+		SELECT `+productColumns("p")+`,
+		       word_similarity($1, p.name) AS rank,
+		       ts_headline('simple', p.name, to_tsquery('simple', $2), $3)
+		FROM products p
+		WHERE word_similarity($1, p.name) >= $4
+		ORDER BY rank DESC, p.id
+		LIMIT $5 OFFSET $6`,
+		q.Text, prefixQuery(q.Text), headlineOptions, fuzzyThreshold, q.Limit, q.Offset)
+
+	return page, err
+}
+
+func (s *Storage) scanSearchResults(query string, args ...any) ([]models.ProductSearchResult, error) {
+	rows, err := s.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.ProductSearchResult{}
+	for rows.Next() {
+		var r models.ProductSearchResult
+		if err := scanProduct(rows, &r.Product, &r.Rank, &r.Snippet); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+// prefixQuery строит tsquery из первых трёх букв каждого слова запроса,
+// объединённых через ИЛИ. Используется только для подсветки нечётких совпадений.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	for _, w := range words {
+		runes := []rune(w)
+		if len(runes) > 3 {
+			runes = runes[:3]
+		}
+		terms = append(terms, string(runes)+":*")
+	}
+
+	if len(terms) == 0 {
+		return "''"
+	}
+	return strings.Join(terms, " | ")
+}
+
+// CreateProduct создаёт товар. Начальный остаток записывается в журнал
+// движений как поступление от actorID.
+func (s *Storage) CreateProduct(p models.Product, actorID int) error {
 	const fn = "storage.postgres.product.CreateProduct"
 
-	_, err := s.db.Exec(context.Background(),
-		`INSERT INTO products (name, price, stock) VALUES ($1, $2, $3)`,
-		p.Name, p.Price, p.Stock)
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback(ctx)
+
+	if _, err := insertProduct(ctx, tx, p, &actorID); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
 
 	return nil
 }
 
+// insertProduct создаёт товар и записывает начальный остаток в журнал
+// движений. Возвращает id товара.
+func insertProduct(ctx context.Context, tx pgx.Tx, p models.Product, actorID *int) (int, error) {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO products (sku, name, price, stock, reorder_threshold, tax_class, weight_grams, pet_min_weight_grams, pet_max_weight_grams, category_id)
+		 VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		p.SKU, p.Name, p.Price, p.Stock, p.ReorderThreshold, p.TaxClass, p.WeightGrams, p.PetMinWeightGrams, p.PetMaxWeightGrams, p.CategoryID).Scan(&p.ID)
+	if err != nil {
+		return 0, productError(err)
+	}
+
+	if err := setProductSpecies(ctx, tx, p.ID, p.Species); err != nil {
+		return 0, err
+	}
+
+	err = recordMovement(ctx, tx, &models.InventoryMovement{
+		ProductID: p.ID,
+		Delta:     p.Stock,
+		Reason:    models.InventoryRestock,
+		ActorID:   actorID,
+		Comment:   "initial stock",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return p.ID, nil
+}
+
 func (s *Storage) DeleteProduct(id int) error {
 	const fn = "storage.postgres.product.DeleteProduct"
 
@@ -52,25 +421,115 @@ func (s *Storage) DeleteProduct(id int) error {
 	return nil
 }
 
-func (s *Storage) UpdateProduct(p models.Product) error {
+// UpdateProduct перезаписывает товар. Если остаток изменился, разница
+// записывается в журнал движений как корректировка от actorID.
+func (s *Storage) UpdateProduct(p models.Product, actorID int) error {
 	const fn = "storage.postgres.product.UpdateProduct"
 
-	_, err := s.db.Exec(context.Background(),
-		`UPDATE products SET name = $1, price = $2, stock = $3 WHERE id = $4`,
-		p.Name, p.Price, p.Stock, p.ID)
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback(ctx)
+
+	if err := updateProduct(ctx, tx, p, &actorID); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
 
 	return nil
 }
 
+// updateProduct — тело UpdateProduct внутри уже открытой транзакции.
+func updateProduct(ctx context.Context, tx pgx.Tx, p models.Product, actorID *int) error {
+	var oldStock int
+	err := tx.QueryRow(ctx, `SELECT stock FROM products WHERE id = $1 FOR UPDATE`, p.ID).Scan(&oldStock)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE products SET sku = NULLIF($1, ''), name = $2, price = $3, stock = $4, reorder_threshold = $5, tax_class = $6, weight_grams = $7,
+		 pet_min_weight_grams = $8, pet_max_weight_grams = $9, category_id = $10 WHERE id = $11`,
+		p.SKU, p.Name, p.Price, p.Stock, p.ReorderThreshold, p.TaxClass, p.WeightGrams, p.PetMinWeightGrams, p.PetMaxWeightGrams, p.CategoryID, p.ID)
+	if err != nil {
+		return productError(err)
+	}
+
+	if err := setProductSpecies(ctx, tx, p.ID, p.Species); err != nil {
+		return err
+	}
+
+	return recordMovement(ctx, tx, &models.InventoryMovement{
+		ProductID: p.ID,
+		Delta:     p.Stock - oldStock,
+		Reason:    models.InventoryAdjustment,
+		ActorID:   actorID,
+		Comment:   "product update",
+	})
+}
+
+// setProductSpecies заменяет список видов животных, для которых подходит товар.
+func setProductSpecies(ctx context.Context, tx pgx.Tx, productID int, species []models.Species) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM product_species WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+	if len(species) == 0 {
+		return nil
+	}
+
+	codes := make([]string, len(species))
+	for i, sp := range species {
+		codes[i] = string(sp)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO product_species (product_id, species_id)
+		SELECT $1, id FROM species WHERE code = ANY($2)`,
+		productID, codes)
+	if err != nil {
+		return err
+	}
+	if int(tag.RowsAffected()) != len(uniqueStrings(codes)) {
+		return storage.ErrUnknownSpecies
+	}
+
+	return nil
+}
+
+func uniqueStrings(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// productError переводит нарушение внешнего ключа category_id и
+// уникальности артикула в ошибки storage.
+func productError(err error) error {
+	switch pgErrorCode(err) {
+	case pgForeignKeyViolation:
+		return storage.ErrCategoryNotFound
+	case pgUniqueViolation:
+		return storage.ErrSKUExists
+	}
+	return err
+}
+
 func (s *Storage) GetProductByID(id int) (models.Product, error) {
 	const fn = "storage.postgres.GetProductByID"
 
-	row := s.db.QueryRow(context.Background(), `SELECT id, name, price, stock FROM products WHERE id = $1`, id)
+	row := s.db.QueryRow(context.Background(), `SELECT `+productColumns("products")+` FROM products WHERE id = $1`, id)
 	var p models.Product
-	if err := row.Scan(&p.ID, &p.Name, &p.Price, &p.Stock); err != nil {
+	if err := scanProduct(row, &p); err != nil {
 		return models.Product{}, fmt.Errorf("%s: %w", fn, err)
 	}
 
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportProducts создаёт и обновляет товары по артикулу в одной транзакции.
+// Каждая строка выполняется в своей точке сохранения, поэтому ошибка в ней
+// не прерывает проверку остальных. Транзакция фиксируется, только если
+// commit == true и ни одна строка не отклонена; иначе отчёт показывает,
+// что было бы сделано. actorID == nil — импорт системой.
+func (s *Storage) ImportProducts(rows []models.ProductImportRow, commit bool, actorID *int) (models.ImportReport, error) {
+	const fn = "storage.postgres.product_import.ImportProducts"
+
+	report := models.ImportReport{Rows: []models.ImportResult{}}
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, row := range rows {
+		res, err := importProductRow(ctx, tx, row, actorID)
+		if err != nil {
+			return report, fmt.Errorf("%s: line %d: %w", fn, row.Line, err)
+		}
+		report.Add(res)
+	}
+
+	if commit && report.Rejected == 0 {
+		if err := tx.Commit(ctx); err != nil {
+			return report, fmt.Errorf("%s: %w", fn, err)
+		}
+		report.Applied = true
+		return report, nil
+	}
+
+	// Новые товары откатываются вместе с транзакцией, их id ничего не значат.
+	for i := range report.Rows {
+		if report.Rows[i].Action == models.ImportCreate {
+			report.Rows[i].ProductID = 0
+		}
+	}
+	return report, nil
+}
+
+// importProductRow применяет одну строку импорта. Ошибки в данных строки
+// возвращаются как ImportReject, ошибка — только если сломалась сама база.
+func importProductRow(ctx context.Context, tx pgx.Tx, row models.ProductImportRow, actorID *int) (models.ImportResult, error) {
+	res := models.ImportResult{Line: row.Line, SKU: row.SKU}
+
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer sp.Rollback(ctx)
+
+	var p models.Product
+	err = scanProduct(sp.QueryRow(ctx, `SELECT `+productColumns("products")+` FROM products WHERE sku = $1 FOR UPDATE`, row.SKU), &p)
+	exists := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return res, err
+	}
+
+	row.Apply(&p)
+	if p.TaxClass == "" {
+		p.TaxClass = models.TaxStandard
+	}
+	if err := p.Validate(); err != nil {
+		return reject(res, err), nil
+	}
+
+	if exists {
+		res.Action, res.ProductID = models.ImportUpdate, p.ID
+		err = updateProduct(ctx, sp, p, actorID)
+	} else {
+		res.Action = models.ImportCreate
+		res.ProductID, err = insertProduct(ctx, sp, p, actorID)
+	}
+	switch {
+	case errors.Is(err, storage.ErrCategoryNotFound), errors.Is(err, storage.ErrUnknownSpecies), errors.Is(err, storage.ErrSKUExists):
+		return reject(res, err), nil
+	case err != nil:
+		return res, err
+	}
+
+	return res, sp.Commit(ctx)
+}
+
+func reject(res models.ImportResult, err error) models.ImportResult {
+	res.Action, res.ProductID, res.Error = models.ImportReject, 0, err.Error()
+	return res
+}
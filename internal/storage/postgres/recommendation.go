@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"go-pet-shop/models"
+)
+
+// RefreshRecommendations пересчитывает product_associations по всем
+// оплаченным и не возвращённым заказам. Пары, купленные вместе реже minSupport раз,
+// отбрасываются как шум. Возвращает число сохранённых пар.
+func (s *Storage) RefreshRecommendations(minSupport int) (int64, error) {
+	const fn = "storage.postgres.recommendation.RefreshRecommendations"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_associations`); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		WITH baskets AS (
+			SELECT DISTINCT oi.order_id, oi.product_id
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.status IN ('paid', 'packed', 'shipped', 'delivered')
+		),
+		total AS (SELECT COUNT(DISTINCT order_id) AS n FROM baskets),
+		counts AS (SELECT product_id, COUNT(*) AS n FROM baskets GROUP BY product_id),
+		pairs AS (
+			SELECT a.product_id, b.product_id AS related_id, COUNT(*) AS n
+			FROM baskets a
+			JOIN baskets b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+			GROUP BY a.product_id, b.product_id
+			HAVING COUNT(*) >= $1
+		)
+		INSERT INTO product_associations (product_id, related_id, support, confidence, lift)
+		SELECT p.product_id, p.related_id, p.n,
+		       p.n::float8 / ca.n,
+		       p.n::float8 * t.n / (ca.n * cb.n)
+		FROM pairs p
+		JOIN counts ca ON ca.product_id = p.product_id
+		JOIN counts cb ON cb.product_id = p.related_id
+		CROSS JOIN total t`, minSupport)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetRecommendations возвращает товары, которые покупают вместе с productID.
+// Товары без доступного остатка не рекомендуются.
+func (s *Storage) GetRecommendations(productID int, q models.RecommendationQuery) ([]models.Recommendation, error) {
+	const fn = "storage.postgres.recommendation.GetRecommendations"
+
+	query := fmt.Sprintf(`
+		SELECT %s, a.support, a.confidence, a.lift
+		FROM product_associations a
+		JOIN products p ON p.id = a.related_id
+		WHERE a.product_id = $1 AND %s > 0
+		ORDER BY a.%s DESC, a.support DESC, p.id
+		LIMIT $2`, productColumns("p"), availableProductStock("p"), scoreColumn(q.Score))
+
+	recs, err := s.queryRecommendations(query, productID, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return recs, nil
+}
+
+// GetRecommendationsForProducts подбирает товары, которые покупают вместе
+// с любым из seed, складывая оценки по всем seed. Товары из exclude
+// и товары без доступного остатка пропускаются.
+func (s *Storage) GetRecommendationsForProducts(seed, exclude []int, q models.RecommendationQuery) ([]models.Recommendation, error) {
+	const fn = "storage.postgres.recommendation.GetRecommendationsForProducts"
+
+	if len(seed) == 0 {
+		return []models.Recommendation{}, nil
+	}
+	if exclude == nil {
+		exclude = []int{}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, SUM(a.support)::int, MAX(a.confidence), MAX(a.lift)
+		FROM product_associations a
+		JOIN products p ON p.id = a.related_id
+		WHERE a.product_id = ANY($1) AND NOT (a.related_id = ANY($2)) AND %s > 0
+		GROUP BY p.id
+		ORDER BY SUM(a.%s) DESC, SUM(a.support) DESC, p.id
+		LIMIT $3`, productColumns("p"), availableProductStock("p"), scoreColumn(q.Score))
+
+	recs, err := s.queryRecommendations(query, seed, exclude, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return recs, nil
+}
+
+func (s *Storage) queryRecommendations(query string, args ...any) ([]models.Recommendation, error) {
+	rows, err := s.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recs := []models.Recommendation{}
+	for rows.Next() {
+		var r models.Recommendation
+		if err := scanProduct(rows, &r.Product, &r.Support, &r.Confidence, &r.Lift); err != nil {
+			return nil, err
+		}
+		recs = append(recs, r)
+	}
+	return recs, rows.Err()
+}
+
+// scoreColumn возвращает колонку product_associations для метрики score,
+// по умолчанию lift.
+func scoreColumn(score models.RecommendationScore) string {
+	if score == models.ScoreConfidence {
+		return "confidence"
+	}
+	return "lift"
+}
@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// verifiedPurchase — у автора отзыва есть доставленный заказ с товаром.
+// Позиция должна быть оформлена с ценой (unit_price): строки, добавленные
+// в заказ в обход оформления, покупку не подтверждают.
+const verifiedPurchase = `EXISTS (SELECT 1 FROM order_items oi JOIN orders o ON o.id = oi.order_id
+	WHERE o.user_id = r.user_id AND oi.product_id = r.product_id AND o.status = 'delivered'
+	  AND oi.unit_price IS NOT NULL)`
+
+// reviewColumns перечисляет колонки отзыва в порядке, который ожидает
+// scanReview. verified вычисляется по доставленным заказам автора.
+const reviewColumns = `r.id, r.product_id, r.user_id, u.name, r.rating, r.title, r.body,
+	` + verifiedPurchase + `,
+	r.status, r.moderation_comment, r.created_at, r.updated_at`
+
+const reviewFrom = ` FROM reviews r JOIN users u ON u.id = r.user_id`
+
+func scanReview(row scanner, r *models.Review) error {
+	return row.Scan(&r.ID, &r.ProductID, &r.UserID, &r.AuthorName, &r.Rating, &r.Title, &r.Body,
+		&r.Verified, &r.Status, &r.ModerationComment, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// GetReviews возвращает страницу отзывов, новые первыми.
+func (s *Storage) GetReviews(q models.ReviewQuery) ([]models.Review, error) {
+	const fn = "storage.postgres.review.GetReviews"
+
+	status := q.Status
+	if status == "" {
+		status = models.ReviewApproved
+	}
+	args := []any{status}
+	where := []string{"r.status = $1"}
+	if q.ProductID != nil {
+		args = append(args, *q.ProductID)
+		where = append(where, fmt.Sprintf("r.product_id = $%d", len(args)))
+	}
+	if q.VerifiedOnly {
+		where = append(where, verifiedPurchase)
+	}
+	args = append(args, q.Limit, q.Offset)
+
+	query := fmt.Sprintf(`SELECT %s%s WHERE %s ORDER BY r.created_at DESC, r.id DESC LIMIT $%d OFFSET $%d`,
+		reviewColumns, reviewFrom, strings.Join(where, " AND "), len(args)-1, len(args))
+	rows, err := s.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		var r models.Review
+		if err := scanReview(rows, &r); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return reviews, nil
+}
+
+func (s *Storage) GetReviewByID(id int) (models.Review, error) {
+	const fn = "storage.postgres.review.GetReviewByID"
+
+	var r models.Review
+	err := scanReview(s.db.QueryRow(context.Background(), `SELECT `+reviewColumns+reviewFrom+` WHERE r.id = $1`, id), &r)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r, fmt.Errorf("%s: %w", fn, storage.ErrReviewNotFound)
+	}
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return r, nil
+}
+
+// CreateReview сохраняет отзыв на модерацию.
+func (s *Storage) CreateReview(r models.Review) (models.Review, error) {
+	const fn = "storage.postgres.review.CreateReview"
+
+	ctx := context.Background()
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO reviews (product_id, user_id, rating, title, body)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`, r.ProductID, r.UserID, r.Rating, r.Title, r.Body).Scan(&id)
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return r, fmt.Errorf("%s: %w", fn, storage.ErrReviewExists)
+	case pgForeignKeyViolation:
+		return r, fmt.Errorf("%s: %w", fn, storage.ErrProductNotFound)
+	}
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return s.GetReviewByID(id)
+}
+
+// UpdateReview меняет оценку и текст отзыва. Изменённый отзыв снова уходит
+// на модерацию и до решения не учитывается в рейтинге.
+func (s *Storage) UpdateReview(r models.Review) (models.Review, error) {
+	const fn = "storage.postgres.review.UpdateReview"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var productID int
+	err = tx.QueryRow(ctx, `
+		UPDATE reviews
+		SET rating = $2, title = $3, body = $4, status = $5, moderation_comment = '', updated_at = NOW()
+		WHERE id = $1
+		RETURNING product_id`, r.ID, r.Rating, r.Title, r.Body, models.ReviewPending).Scan(&productID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r, fmt.Errorf("%s: %w", fn, storage.ErrReviewNotFound)
+	}
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := refreshProductRating(ctx, tx, productID); err != nil {
+		return r, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return r, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return s.GetReviewByID(r.ID)
+}
+
+// ModerateReview переводит отзыв в status и пересчитывает рейтинг товара.
+func (s *Storage) ModerateReview(id int, status models.ReviewStatus, comment string) (models.Review, error) {
+	const fn = "storage.postgres.review.ModerateReview"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Review{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var productID int
+	err = tx.QueryRow(ctx, `
+		UPDATE reviews SET status = $2, moderation_comment = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING product_id`, id, status, comment).Scan(&productID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Review{}, fmt.Errorf("%s: %w", fn, storage.ErrReviewNotFound)
+	}
+	if err != nil {
+		return models.Review{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := refreshProductRating(ctx, tx, productID); err != nil {
+		return models.Review{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Review{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return s.GetReviewByID(id)
+}
+
+func (s *Storage) DeleteReview(id int) error {
+	const fn = "storage.postgres.review.DeleteReview"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var productID int
+	err = tx.QueryRow(ctx, `DELETE FROM reviews WHERE id = $1 RETURNING product_id`, id).Scan(&productID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s: %w", fn, storage.ErrReviewNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := refreshProductRating(ctx, tx, productID); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// refreshProductRating пересчитывает сводный рейтинг товара по одобренным
+// отзывам.
+func refreshProductRating(ctx context.Context, tx pgx.Tx, productID int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE products p
+		SET rating_avg = COALESCE(a.avg, 0), rating_count = a.count
+		FROM (SELECT ROUND(AVG(rating), 2) AS avg, COUNT(*) AS count
+		      FROM reviews WHERE product_id = $1 AND status = $2) a
+		WHERE p.id = $1`, productID, models.ReviewApproved)
+	return err
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetShippingMethods возвращает активные способы доставки с тарифами.
+func (s *Storage) GetShippingMethods() ([]models.ShippingMethod, error) {
+	const fn = "storage.postgres.shipping.GetShippingMethods"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT m.id, m.code, m.name, r.zone_code, r.max_weight_grams, r.price, r.free_threshold
+		FROM shipping_methods m
+		JOIN shipping_rates r ON r.method_id = m.id
+		WHERE m.active
+		ORDER BY m.id, r.zone_code, r.max_weight_grams NULLS LAST`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	methods := []models.ShippingMethod{}
+	for rows.Next() {
+		var m models.ShippingMethod
+		var rate models.ShippingRate
+		err := rows.Scan(&m.ID, &m.Code, &m.Name, &rate.Zone, &rate.MaxWeightGrams, &rate.Price, &rate.FreeThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		if n := len(methods); n == 0 || methods[n-1].ID != m.ID {
+			methods = append(methods, m)
+		}
+		last := &methods[len(methods)-1]
+		last.Rates = append(last.Rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return methods, nil
+}
+
+func (s *Storage) GetOrderShipment(orderID int) (*models.OrderShipment, error) {
+	const fn = "storage.postgres.shipping.GetOrderShipment"
+
+	var sh models.OrderShipment
+	err := s.db.QueryRow(context.Background(), `
+		SELECT order_id, address_id, method_code, method_name, zone_code, recipient, phone,
+		       country, city, postal_code, line1, line2, weight_grams, cost
+		FROM order_shipments
+		WHERE order_id = $1`, orderID).
+		Scan(&sh.OrderID, &sh.AddressID, &sh.MethodCode, &sh.MethodName, &sh.Zone, &sh.Recipient, &sh.Phone,
+			&sh.Country, &sh.City, &sh.PostalCode, &sh.Line1, &sh.Line2, &sh.WeightGrams, &sh.Cost)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil // заказ без доставки
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &sh, nil
+}
+
+// applyShipping оформляет доставку заказа orderID по адресу addressID
+// покупателя userID способом methodCode. Тариф выбирается по зоне страны
+// адреса и весу позиций, subtotal — сумма заказа после скидки для порога
+// бесплатной доставки. Возвращает стоимость доставки.
+func applyShipping(ctx context.Context, tx pgx.Tx, orderID, userID, addressID int, methodCode string, subtotal models.Money) (models.Money, error) {
+	if methodCode == "" {
+		return 0, storage.ErrShippingMethodRequired
+	}
+
+	sh := models.OrderShipment{OrderID: orderID, AddressID: &addressID}
+	err := tx.QueryRow(ctx, `
+		SELECT recipient, phone, country, city, postal_code, line1, line2
+		FROM addresses
+		WHERE id = $1 AND user_id = $2`, addressID, userID).
+		Scan(&sh.Recipient, &sh.Phone, &sh.Country, &sh.City, &sh.PostalCode, &sh.Line1, &sh.Line2)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrAddressNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var methodID int
+	err = tx.QueryRow(ctx, `SELECT id, code, name FROM shipping_methods WHERE code = $1 AND active`, methodCode).
+		Scan(&methodID, &sh.MethodCode, &sh.MethodName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrShippingMethodNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(p.weight_grams * oi.quantity), 0)
+		FROM order_items oi
+		JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id = $1`, orderID).Scan(&sh.WeightGrams)
+	if err != nil {
+		return 0, err
+	}
+
+	var rate models.ShippingRate
+	err = tx.QueryRow(ctx, `
+		SELECT r.zone_code, r.max_weight_grams, r.price, r.free_threshold
+		FROM shipping_rates r
+		JOIN shipping_zones z ON z.code = r.zone_code
+		WHERE r.method_id = $1
+		  AND $2::char(2) = ANY(z.countries)
+		  AND (r.max_weight_grams IS NULL OR r.max_weight_grams >= $3)
+		ORDER BY r.max_weight_grams NULLS LAST
+		LIMIT 1`, methodID, sh.Country, sh.WeightGrams).
+		Scan(&rate.Zone, &rate.MaxWeightGrams, &rate.Price, &rate.FreeThreshold)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrShippingUnavailable
+	}
+	if err != nil {
+		return 0, err
+	}
+	sh.Zone = rate.Zone
+	sh.Cost = rate.Cost(subtotal)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO order_shipments (order_id, address_id, method_code, method_name, zone_code, recipient, phone,
+		                             country, city, postal_code, line1, line2, weight_grams, cost)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		sh.OrderID, sh.AddressID, sh.MethodCode, sh.MethodName, sh.Zone, sh.Recipient, sh.Phone,
+		sh.Country, sh.City, sh.PostalCode, sh.Line1, sh.Line2, sh.WeightGrams, sh.Cost)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE orders SET shipping_total = $1 WHERE id = $2`, sh.Cost, orderID)
+	if err != nil {
+		return 0, err
+	}
+
+	return sh.Cost, nil
+}
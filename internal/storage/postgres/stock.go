@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// availableProductStock и availableVariantStock — доступный остаток:
+// складской минус активные резервы. Резерв перестаёт учитываться только
+// когда его снимет sweeper, поэтому просроченный, но ещё не снятый резерв
+// не даёт продать один и тот же товар дважды.
+func availableProductStock(alias string) string {
+	return fmt.Sprintf(`GREATEST(%[1]s.stock - COALESCE((
+		SELECT SUM(r.quantity) FROM stock_reservations r
+		WHERE r.product_id = %[1]s.id AND r.variant_id IS NULL AND r.status = 'active'), 0), 0)`, alias)
+}
+
+func availableVariantStock(alias string) string {
+	return fmt.Sprintf(`GREATEST(%[1]s.stock - COALESCE((
+		SELECT SUM(r.quantity) FROM stock_reservations r
+		WHERE r.variant_id = %[1]s.id AND r.status = 'active'), 0), 0)`, alias)
+}
+
+// reserveOrderItem возвращает цену позиции заказа и резервирует её остаток
+// на ttl: с варианта, если он указан, иначе с товара. Для товаров
+// с вариантами variant_id обязателен. Строка товара (варианта) блокируется,
+// чтобы параллельные заказы не зарезервировали один и тот же остаток.
+func reserveOrderItem(ctx context.Context, tx pgx.Tx, orderID int, item models.OrderItem, ttl time.Duration) (models.Money, error) {
+	var price models.Money
+	var available int
+
+	if item.VariantID == nil {
+		var hasVariants bool
+		err := tx.QueryRow(ctx, `
+			SELECT p.price, `+availableProductStock("p")+`,
+			       EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id)
+			FROM products p WHERE p.id = $1
+			FOR UPDATE OF p`, item.ProductID).Scan(&price, &available, &hasVariants)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("product %d: %w", item.ProductID, storage.ErrProductNotFound)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if hasVariants {
+			return 0, fmt.Errorf("product %d: %w", item.ProductID, storage.ErrVariantRequired)
+		}
+		if available < item.Quantity {
+			return 0, fmt.Errorf("product %d: %w", item.ProductID, storage.ErrOutOfStock)
+		}
+	} else {
+		err := tx.QueryRow(ctx, `
+			SELECT v.price, `+availableVariantStock("v")+`
+			FROM product_variants v WHERE v.id = $1 AND v.product_id = $2
+			FOR UPDATE OF v`, *item.VariantID, item.ProductID).Scan(&price, &available)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("variant %d: %w", *item.VariantID, storage.ErrVariantNotFound)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if available < item.Quantity {
+			return 0, fmt.Errorf("variant %d: %w", *item.VariantID, storage.ErrOutOfStock)
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO stock_reservations (order_id, product_id, variant_id, quantity, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5::float8 * INTERVAL '1 second')`,
+		orderID, item.ProductID, item.VariantID, item.Quantity, ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return price, nil
+}
+
+// commitReservations списывает со склада активные резервы оплаченного заказа.
+func commitReservations(ctx context.Context, tx pgx.Tx, orderID int) error {
+	if err := applyReservations(ctx, tx, orderID, models.ReservationActive, -1, models.InventorySale, nil); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE stock_reservations SET status = $2, updated_at = NOW()
+		WHERE order_id = $1 AND status = $3`,
+		orderID, models.ReservationCommitted, models.ReservationActive)
+	return err
+}
+
+// releaseReservations снимает резервы отменённого или возвращённого заказа:
+// активные просто перестают учитываться, а уже списанные возвращаются на
+// склад с причиной reason. actorID == nil — изменение системой.
+func releaseReservations(ctx context.Context, tx pgx.Tx, orderID int, reason models.InventoryReason, actorID *int) error {
+	if err := applyReservations(ctx, tx, orderID, models.ReservationCommitted, 1, reason, actorID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE stock_reservations SET status = $2, updated_at = NOW()
+		WHERE order_id = $1 AND status IN ($3, $4)`,
+		orderID, models.ReservationReleased, models.ReservationActive, models.ReservationCommitted)
+	return err
+}
+
+// applyReservations меняет складской остаток на sign * quantity по резервам
+// заказа в статусе from и записывает каждое изменение в журнал движений
+// с причиной reason и ссылкой на заказ.
+func applyReservations(ctx context.Context, tx pgx.Tx, orderID int, from models.ReservationStatus, sign int,
+	reason models.InventoryReason, actorID *int) error {
+	rows, err := tx.Query(ctx, `
+		SELECT product_id, variant_id, SUM(quantity)
+		FROM stock_reservations
+		WHERE order_id = $1 AND status = $2
+		GROUP BY product_id, variant_id
+		ORDER BY product_id, variant_id NULLS FIRST`, orderID, from)
+	if err != nil {
+		return err
+	}
+	movements, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.InventoryMovement, error) {
+		m := models.InventoryMovement{Reason: reason, ReferenceID: &orderID, ActorID: actorID}
+		err := row.Scan(&m.ProductID, &m.VariantID, &m.Delta)
+		m.Delta *= sign
+		return m, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range movements {
+		if m.VariantID == nil {
+			_, err = tx.Exec(ctx, `UPDATE products SET stock = stock + $1 WHERE id = $2`, m.Delta, m.ProductID)
+		} else {
+			_, err = tx.Exec(ctx, `UPDATE product_variants SET stock = stock + $1 WHERE id = $2`, m.Delta, *m.VariantID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := recordMovement(ctx, tx, &m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpireReservations снимает резервы, не оплаченные за TTL, и отменяет их
+// заказы. Каждый заказ обрабатывается в своей транзакции, занятые другими
+// транзакциями заказы пропускаются до следующего прохода. Возвращает ID
+// отменённых заказов, чтобы вызывающий вернул по ним деньги.
+func (s *Storage) ExpireReservations(limit int) ([]int, error) {
+	const fn = "storage.postgres.stock.ExpireReservations"
+
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, `
+		SELECT DISTINCT order_id FROM stock_reservations
+		WHERE status = $1 AND expires_at <= NOW()
+		LIMIT $2`, models.ReservationActive, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	orderIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var expired []int
+	for _, orderID := range orderIDs {
+		ok, err := s.expireOrder(ctx, orderID)
+		if err != nil {
+			return expired, fmt.Errorf("%s: order %d: %w", fn, orderID, err)
+		}
+		if ok {
+			expired = append(expired, orderID)
+		}
+	}
+
+	return expired, nil
+}
+
+// expireOrder отменяет заказ с просроченным резервом. Если заказ занят
+// (например, прямо сейчас приходит оплата) или резерв уже снят, ничего не
+// делает и возвращает false. Резерв заказа, который уже ушёл из
+// pending_payment, не истекает, а списывается со склада: товар продан.
+func (s *Storage) expireOrder(ctx context.Context, orderID int) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status models.OrderStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE SKIP LOCKED`, orderID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if status != models.OrderPendingPayment {
+		if err := commitReservations(ctx, tx, orderID); err != nil {
+			return false, err
+		}
+		return false, tx.Commit(ctx)
+	}
+
+	// Между выборкой и блокировкой заказ могли оплатить или отменить.
+	tag, err := tx.Exec(ctx, `
+		UPDATE stock_reservations SET status = $2, updated_at = NOW()
+		WHERE order_id = $1 AND status = $3 AND expires_at <= NOW()`,
+		orderID, models.ReservationExpired, models.ReservationActive)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+
+	if _, err := cancelOrder(ctx, tx, orderID, nil, "reservation expired"); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
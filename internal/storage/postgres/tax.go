@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetTaxRegions возвращает налоговые регионы вместе со ставками. Регионы
+// и ставки читаются одним запросом, чтобы видеть один снимок данных.
+func (s *Storage) GetTaxRegions() ([]models.TaxRegion, error) {
+	const fn = "storage.postgres.tax.GetTaxRegions"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT r.code, r.name, r.prices_include_tax, t.tax_class, t.rate_bp
+		FROM tax_regions r
+		LEFT JOIN tax_rates t ON t.region_code = r.code
+		ORDER BY r.code, t.tax_class`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	regions := []models.TaxRegion{}
+	for rows.Next() {
+		var r models.TaxRegion
+		var class *models.TaxClass
+		var rate *int
+		if err := rows.Scan(&r.Code, &r.Name, &r.PricesIncludeTax, &class, &rate); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		if n := len(regions); n == 0 || regions[n-1].Code != r.Code {
+			r.Rates = map[models.TaxClass]int{}
+			regions = append(regions, r)
+		}
+		if class != nil && rate != nil {
+			regions[len(regions)-1].Rates[*class] = *rate
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return regions, nil
+}
+
+// getTaxRegion загружает регион со ставками. Код не зависит от регистра.
+func getTaxRegion(ctx context.Context, q querier, code string) (models.TaxRegion, error) {
+	r := models.TaxRegion{Rates: map[models.TaxClass]int{}}
+	err := q.QueryRow(ctx, `SELECT code, name, prices_include_tax FROM tax_regions WHERE code = UPPER($1)`, code).
+		Scan(&r.Code, &r.Name, &r.PricesIncludeTax)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r, storage.ErrTaxRegionNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+
+	rows, err := q.Query(ctx, `SELECT tax_class, rate_bp FROM tax_rates WHERE region_code = $1`, r.Code)
+	if err != nil {
+		return r, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var class models.TaxClass
+		var rate int
+		if err := rows.Scan(&class, &rate); err != nil {
+			return r, err
+		}
+		r.Rates[class] = rate
+	}
+	return r, rows.Err()
+}
+
+// SaveTaxRegion создаёт или обновляет регион и заменяет его ставки.
+// Уже оформленные заказы хранят свои строки налога и не меняются.
+func (s *Storage) SaveTaxRegion(r models.TaxRegion) error {
+	const fn = "storage.postgres.tax.SaveTaxRegion"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tax_regions (code, name, prices_include_tax) VALUES ($1, $2, $3)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name, prices_include_tax = EXCLUDED.prices_include_tax`,
+		r.Code, r.Name, r.PricesIncludeTax)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM tax_rates WHERE region_code = $1`, r.Code); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	for class, rate := range r.Rates {
+		_, err = tx.Exec(ctx, `INSERT INTO tax_rates (region_code, tax_class, rate_bp) VALUES ($1, $2, $3)`,
+			r.Code, class, rate)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetOrderTaxLines(orderID int) ([]models.OrderTaxLine, error) {
+	const fn = "storage.postgres.tax.GetOrderTaxLines"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT tax_class, rate_bp, taxable_amount, amount
+		FROM order_tax_lines
+		WHERE order_id = $1
+		ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	lines, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OrderTaxLine, error) {
+		var l models.OrderTaxLine
+		err := row.Scan(&l.TaxClass, &l.RateBP, &l.TaxableAmount, &l.Amount)
+		return l, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return lines, nil
+}
+
+// applyTax считает налог заказа orderID по ставкам региона regionCode
+// с учётом скидки discount, записывает строки налога и сохраняет регион
+// и режим цен в заказе. Возвращает сумму налога и режим цен.
+func applyTax(ctx context.Context, tx pgx.Tx, orderID int, regionCode string, discount models.Money) (models.Money, bool, error) {
+	region, err := getTaxRegion(ctx, tx, regionCode)
+	if err != nil {
+		return 0, false, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT p.tax_class, SUM(oi.unit_price * oi.quantity)
+		FROM order_items oi
+		JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id = $1
+		GROUP BY p.tax_class`, orderID)
+	if err != nil {
+		return 0, false, err
+	}
+	classTotals := map[models.TaxClass]models.Money{}
+	for rows.Next() {
+		var class models.TaxClass
+		var total models.Money
+		if err := rows.Scan(&class, &total); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		classTotals[class] = total
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+
+	var tax models.Money
+	for _, line := range models.CalculateTax(region, classTotals, discount) {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO order_tax_lines (order_id, tax_class, rate_bp, taxable_amount, amount)
+			VALUES ($1, $2, $3, $4, $5)`,
+			orderID, line.TaxClass, line.RateBP, line.TaxableAmount, line.Amount)
+		if err != nil {
+			return 0, false, err
+		}
+		tax += line.Amount
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE orders SET tax_region = $1, prices_include_tax = $2, tax_total = $3
+		WHERE id = $4`, region.Code, region.PricesIncludeTax, tax, orderID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return tax, region.PricesIncludeTax, nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pet-shop/internal/storage"
+	"go-pet-shop/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) GetProductVariants(productID int) ([]models.ProductVariant, error) {
+	const fn = "storage.postgres.variant.GetProductVariants"
+
+	rows, err := s.db.Query(context.Background(), `
+		SELECT v.id, v.product_id, v.sku, v.price, v.stock, `+availableVariantStock("v")+`, v.attributes
+		FROM product_variants v
+		WHERE v.product_id = $1
+		ORDER BY v.price, v.id`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	variants := []models.ProductVariant{}
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Price, &v.Stock, &v.Available, &v.Attributes); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return variants, nil
+}
+
+// CreateProductVariant создаёт вариант. Начальный остаток записывается
+// в журнал движений как поступление от actorID.
+func (s *Storage) CreateProductVariant(v models.ProductVariant, actorID int) (int, error) {
+	const fn = "storage.postgres.variant.CreateProductVariant"
+
+	if v.Attributes == nil {
+		v.Attributes = map[string]string{}
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO product_variants (product_id, sku, price, stock, attributes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		v.ProductID, v.SKU, v.Price, v.Stock, v.Attributes).Scan(&v.ID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, variantError(err))
+	}
+
+	err = recordMovement(ctx, tx, &models.InventoryMovement{
+		ProductID: v.ProductID,
+		VariantID: &v.ID,
+		Delta:     v.Stock,
+		Reason:    models.InventoryRestock,
+		ActorID:   &actorID,
+		Comment:   "initial stock",
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return v.ID, nil
+}
+
+// UpdateProductVariant перезаписывает вариант. Изменение остатка
+// записывается в журнал движений как корректировка от actorID.
+func (s *Storage) UpdateProductVariant(v models.ProductVariant, actorID int) error {
+	const fn = "storage.postgres.variant.UpdateProductVariant"
+
+	if v.Attributes == nil {
+		v.Attributes = map[string]string{}
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldStock int
+	err = tx.QueryRow(ctx, `
+		SELECT stock FROM product_variants
+		WHERE id = $1 AND product_id = $2
+		FOR UPDATE`, v.ID, v.ProductID).Scan(&oldStock)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s: %w", fn, storage.ErrVariantNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE product_variants
+		SET sku = $1, price = $2, stock = $3, attributes = $4
+		WHERE id = $5`,
+		v.SKU, v.Price, v.Stock, v.Attributes, v.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, variantError(err))
+	}
+
+	err = recordMovement(ctx, tx, &models.InventoryMovement{
+		ProductID: v.ProductID,
+		VariantID: &v.ID,
+		Delta:     v.Stock - oldStock,
+		Reason:    models.InventoryAdjustment,
+		ActorID:   &actorID,
+		Comment:   "variant update",
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteProductVariant(productID, variantID int) error {
+	const fn = "storage.postgres.variant.DeleteProductVariant"
+
+	tag, err := s.db.Exec(context.Background(),
+		`DELETE FROM product_variants WHERE id = $1 AND product_id = $2`, variantID, productID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrVariantNotFound)
+	}
+
+	return nil
+}
+
+// variantError переводит нарушения ограничений product_variants в ошибки storage.
+func variantError(err error) error {
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return storage.ErrSKUExists
+	case pgForeignKeyViolation:
+		return storage.ErrProductNotFound
+	}
+	return err
+}
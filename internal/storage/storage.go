@@ -12,14 +12,12 @@ type PostgresStorage struct {
 }
 
 type Storage interface {
-	GetAllProducts() ([]models.Product, error)
-    GetPopularProducts() ([]models.PopularProduct, error)
-	CreateProduct(product models.Product) error
-	UpdateProduct(product models.Product) error
+	GetAllProducts(query models.ProductQuery) (models.ProductPage, error)
+    GetPopularProducts(query models.PopularQuery) ([]models.PopularProduct, error)
+	CreateProduct(product models.Product, actorID int) error
+	UpdateProduct(product models.Product, actorID int) error
 	DeleteProduct(id int) error
 
-	CreateOrder(order models.Order) (int, error)
-	AddOrderItem(item models.OrderItem) error
 	GetOrderByID(id int) (models.Order, error)
 	GetOrdersByUserEmail(email string) ([]models.Order, error)
 	GetOrderItemsByOrderID(orderID int) ([]models.OrderItem, error) 
@@ -27,8 +25,11 @@ type Storage interface {
     CreateUser(user models.User) error
     GetUserByEmail(email string) (models.User, error)
     GetAllUsers() ([]models.User, error)
+    GetCustomerRFM(userID int) (*models.CustomerRFM, error)
 	}
 
+	// Deprecated: списывает остаток сразу, без резерва. Заказы оформляет
+	// postgres.Storage.PlaceOrder.
 	func (s *PostgresStorage) PlaceOrder(userEmail string, items []models.OrderItem) (orderID int, err error) {
     tx, err := s.db.Begin()
     if err != nil {
@@ -60,16 +61,16 @@ type Storage interface {
     }
 
     // Создаем заказ
-    totalPrice := 0.0
+    var totalPrice models.Money
 	
     // Можно посчитать сумму или сделать отдельный запрос
     for _, item := range items {
-        var price float64
+        var price models.Money
         err = tx.QueryRow("SELECT price FROM products WHERE id = $1", item.ProductID).Scan(&price)
         if err != nil {
             return 0, err
         }
-        totalPrice += price * float64(item.Quantity)
+        totalPrice += price.Mul(item.Quantity)
     }
 
     err = tx.QueryRow(
@@ -119,9 +120,14 @@ func (s *PostgresStorage) GetUserOrderHistory(email string) ([]models.OrderDetai
         JOIN orders o ON u.id = o.user_id
         JOIN order_items oi ON o.id = oi.order_id
         JOIN products p ON oi.product_id = p.id
-        JOIN transactions t ON o.id = t.order_id
+        JOIN LATERAL (
+            SELECT status FROM transactions
+            WHERE order_id = o.id
+            ORDER BY id DESC
+            LIMIT 1
+        ) t ON true
         WHERE u.email = $1
-        ORDER BY o.created_at DESC;
+        ORDER BY o.created_at DESC, o.id DESC, oi.id;
     `
 
     rows, err := s.db.Query(query, email)
@@ -163,7 +169,7 @@ func (s *PostgresStorage) GetUserByEmail(email string) (models.User, error) {
     return user, err
 }
 
-func (s *Storage) GetAllUsers() ([]models.User, error) {
+func (s *PostgresStorage) GetAllUsers() ([]models.User, error) {
     var users []models.User
     rows, err := s.db.Query("SELECT email, name FROM users")
     if err != nil {
@@ -184,6 +190,43 @@ func (s *Storage) GetAllUsers() ([]models.User, error) {
 }
 
 var (
-	ErrURLNotFound = errors.New("url not found")
-	ErrURLExists   = errors.New("url already exists")
+	ErrURLNotFound   = errors.New("url not found")
+	ErrURLExists     = errors.New("url already exists")
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrCategoryHasChildren = errors.New("category has subcategories")
+	ErrCategoryCycle       = errors.New("category cannot be moved into its own subtree")
+	ErrCategoryExists      = errors.New("category slug already exists")
+	ErrUnknownSpecies      = errors.New("unknown species")
+
+	ErrProductNotFound = errors.New("product not found")
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrVariantRequired = errors.New("product has variants, variant_id is required")
+	ErrSKUExists       = errors.New("sku already exists")
+	ErrOutOfStock      = errors.New("not enough stock")
+
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrEmptyOrder      = errors.New("order has no items")
+
+	ErrCartNotFound     = errors.New("cart not found")
+	ErrCartItemNotFound = errors.New("cart item not found")
+	ErrCartClosed       = errors.New("cart is already checked out")
+
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponExists        = errors.New("coupon code already exists")
+	ErrCouponNotApplicable = errors.New("coupon cannot be applied")
+	ErrTaxRegionNotFound   = errors.New("tax region not found")
+
+	ErrUserNotFound           = errors.New("user not found")
+	ErrAddressNotFound        = errors.New("address not found")
+	ErrShippingMethodRequired = errors.New("shipping method is required")
+	ErrShippingMethodNotFound = errors.New("shipping method not found")
+	ErrShippingUnavailable    = errors.New("shipping is not available for this address")
+
+	ErrReviewNotFound = errors.New("review not found")
+	ErrReviewExists   = errors.New("product already reviewed by this user")
+
+	ErrPetNotFound = errors.New("pet not found")
 )
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_created_at_id;
+DROP INDEX IF EXISTS idx_products_name_id;
+DROP INDEX IF EXISTS idx_products_price_id;
+
+ALTER TABLE products DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,32 @@
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INT REFERENCES categories(id) ON DELETE RESTRICT,
+    name TEXT NOT NULL,
+    slug TEXT UNIQUE NOT NULL,
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+
+CREATE TABLE species (
+    id SERIAL PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL
+);
+
+INSERT INTO species (code, name) VALUES
+    ('dog', 'Dogs'),
+    ('cat', 'Cats'),
+    ('bird', 'Birds'),
+    ('fish', 'Fish'),
+    ('reptile', 'Reptiles'),
+    ('small_mammal', 'Small mammals');
+
+ALTER TABLE products ADD COLUMN category_id INT REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(category_id);
+
+CREATE TABLE product_species (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    species_id INT NOT NULL REFERENCES species(id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, species_id)
+);
+CREATE INDEX IF NOT EXISTS idx_product_species_species_id ON product_species(species_id);
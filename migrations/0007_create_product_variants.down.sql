@@ -0,0 +1,2 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS product_variants;
@@ -0,0 +1,12 @@
+CREATE TABLE product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku TEXT UNIQUE NOT NULL,
+    price NUMERIC NOT NULL,
+    stock INT NOT NULL CHECK (stock >= 0),
+    attributes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+
+ALTER TABLE order_items ADD COLUMN variant_id INT REFERENCES product_variants(id);
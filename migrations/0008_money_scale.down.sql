@@ -0,0 +1,4 @@
+ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC;
+ALTER TABLE orders ALTER COLUMN total_price TYPE NUMERIC;
+ALTER TABLE product_variants ALTER COLUMN price TYPE NUMERIC;
+ALTER TABLE products ALTER COLUMN price TYPE NUMERIC;
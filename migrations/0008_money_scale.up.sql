@@ -0,0 +1,5 @@
+-- Денежные суммы хранятся с точностью до копейки, см. models.Money.
+ALTER TABLE products ALTER COLUMN price TYPE NUMERIC(12, 2);
+ALTER TABLE product_variants ALTER COLUMN price TYPE NUMERIC(12, 2);
+ALTER TABLE orders ALTER COLUMN total_price TYPE NUMERIC(12, 2);
+ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC(12, 2);
@@ -0,0 +1,21 @@
+ALTER TABLE orders
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending_payment'
+    CHECK (status IN ('pending_payment', 'paid', 'packed', 'shipped', 'delivered', 'cancelled', 'refunded'));
+
+-- Заказы, по которым уже прошла оплата, считаем оплаченными.
+UPDATE orders o SET status = 'paid'
+WHERE EXISTS (SELECT 1 FROM transactions t WHERE t.order_id = o.id AND t.status = 'completed');
+
+CREATE TABLE order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    changed_by INT REFERENCES users(id),
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
+
+INSERT INTO order_status_history (order_id, from_status, to_status, created_at)
+SELECT id, NULL, status, created_at FROM orders;
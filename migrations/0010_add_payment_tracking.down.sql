@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS transaction_events;
+DROP INDEX IF EXISTS idx_transactions_gateway_reference;
+ALTER TABLE transactions DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE transactions DROP COLUMN IF EXISTS gateway_reference;
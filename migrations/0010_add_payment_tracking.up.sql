@@ -0,0 +1,16 @@
+ALTER TABLE transactions ADD COLUMN gateway_reference TEXT;
+ALTER TABLE transactions ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_gateway_reference ON transactions(gateway_reference);
+
+CREATE TABLE transaction_events (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    gateway_reference TEXT,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_transaction_events_transaction_id ON transaction_events(transaction_id);
+
+INSERT INTO transaction_events (transaction_id, status, created_at)
+SELECT id, status, created_at FROM transactions;
@@ -0,0 +1,15 @@
+-- До резервов остаток списывался при оформлении: списываем активные резервы,
+-- чтобы неоплаченные заказы снова держали свой товар.
+UPDATE products p SET stock = p.stock - r.quantity
+FROM (SELECT product_id, SUM(quantity) AS quantity FROM stock_reservations
+      WHERE status = 'active' AND variant_id IS NULL
+      GROUP BY product_id) r
+WHERE p.id = r.product_id;
+
+UPDATE product_variants v SET stock = v.stock - r.quantity
+FROM (SELECT variant_id, SUM(quantity) AS quantity FROM stock_reservations
+      WHERE status = 'active' AND variant_id IS NOT NULL
+      GROUP BY variant_id) r
+WHERE v.id = r.variant_id;
+
+DROP TABLE IF EXISTS stock_reservations;
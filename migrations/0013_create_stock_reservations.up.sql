@@ -0,0 +1,67 @@
+-- Резерв остатка под заказ, ожидающий оплаты. Пока резерв active, товар
+-- недоступен другим покупателям, но со склада (products.stock,
+-- product_variants.stock) ещё не списан: списание происходит при оплате
+-- (committed). Неоплаченный за TTL резерв снимает фоновый sweeper (expired),
+-- отмена заказа — released.
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INT REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'committed', 'released', 'expired')),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_order_id ON stock_reservations(order_id);
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active_product
+    ON stock_reservations(product_id) WHERE status = 'active' AND variant_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active_variant
+    ON stock_reservations(variant_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active_expires_at
+    ON stock_reservations(expires_at) WHERE status = 'active';
+
+-- Резервы для существующих заказов. Остаток вариантов списывался при
+-- оформлении всегда, остаток товаров без вариантов — только в последних
+-- версиях. Надёжный признак таких заказов — платёж через шлюз: у старых
+-- заказов он completed. Заказы, чей остаток не списывался, резервов не
+-- получают, и их отмена ничего не вернёт на склад.
+CREATE TEMP TABLE legacy_order_stock AS
+SELECT oi.order_id, o.status, oi.product_id, oi.variant_id, oi.quantity,
+       COALESCE(o.created_at, CURRENT_TIMESTAMP) AS created_at,
+       (oi.variant_id IS NOT NULL OR EXISTS (
+           SELECT 1 FROM transactions t WHERE t.order_id = oi.order_id AND t.status <> 'completed'
+       )) AS taken
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+WHERE o.status <> 'cancelled' AND oi.quantity > 0;
+
+-- Оплаченные заказы: остаток списан, отмена его вернёт.
+INSERT INTO stock_reservations (order_id, product_id, variant_id, quantity, status, expires_at)
+SELECT order_id, product_id, variant_id, quantity, 'committed', created_at
+FROM legacy_order_stock
+WHERE taken AND status <> 'pending_payment';
+
+-- Неоплаченные заказы переводим на резервы: списанный остаток возвращается
+-- на склад, а позиции получают уже истёкший active-резерв. Sweeper отменит
+-- такой заказ, а если оплата всё же придёт, резерв спишется при оплате.
+UPDATE products p SET stock = p.stock + l.quantity
+FROM (SELECT product_id, SUM(quantity) AS quantity FROM legacy_order_stock
+      WHERE taken AND status = 'pending_payment' AND variant_id IS NULL
+      GROUP BY product_id) l
+WHERE p.id = l.product_id;
+
+UPDATE product_variants v SET stock = v.stock + l.quantity
+FROM (SELECT variant_id, SUM(quantity) AS quantity FROM legacy_order_stock
+      WHERE taken AND status = 'pending_payment' AND variant_id IS NOT NULL
+      GROUP BY variant_id) l
+WHERE v.id = l.variant_id;
+
+INSERT INTO stock_reservations (order_id, product_id, variant_id, quantity, status, expires_at)
+SELECT order_id, product_id, variant_id, quantity, 'active', CURRENT_TIMESTAMP
+FROM legacy_order_stock
+WHERE status = 'pending_payment';
+
+DROP TABLE legacy_order_stock;
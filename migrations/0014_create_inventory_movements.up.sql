@@ -0,0 +1,36 @@
+-- Журнал движений остатков. Каждое изменение products.stock и
+-- product_variants.stock записывается сюда, поэтому сумма delta по товару
+-- (варианту) должна совпадать с его остатком. Внешних ключей на товары
+-- и пользователей нет намеренно: история остаётся и после их удаления,
+-- а ON DELETE SET NULL упёрся бы в запрет UPDATE журнала.
+CREATE TABLE IF NOT EXISTS inventory_movements (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    variant_id INT,
+    delta INT NOT NULL CHECK (delta <> 0),
+    reason TEXT NOT NULL
+        CHECK (reason IN ('sale', 'cancellation', 'adjustment', 'restock', 'return')),
+    reference_id INT,           -- для sale, cancellation и return — id заказа
+    actor_id INT,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_product_id ON inventory_movements(product_id, variant_id);
+
+-- Журнал только дополняется.
+CREATE OR REPLACE FUNCTION inventory_movements_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'inventory_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER inventory_movements_append_only
+    BEFORE UPDATE OR DELETE ON inventory_movements
+    FOR EACH ROW EXECUTE FUNCTION inventory_movements_append_only();
+
+-- Начальные остатки, чтобы журнал сходился с текущим складом.
+INSERT INTO inventory_movements (product_id, delta, reason, comment)
+SELECT id, stock, 'adjustment', 'opening balance' FROM products WHERE stock <> 0;
+
+INSERT INTO inventory_movements (product_id, variant_id, delta, reason, comment)
+SELECT product_id, id, stock, 'adjustment', 'opening balance' FROM product_variants WHERE stock <> 0;
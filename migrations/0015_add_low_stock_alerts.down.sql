@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS low_stock_alerts;
+DROP INDEX IF EXISTS idx_products_low_stock;
+ALTER TABLE products DROP COLUMN IF EXISTS reorder_threshold;
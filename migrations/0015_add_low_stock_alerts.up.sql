@@ -0,0 +1,15 @@
+-- Порог дозаказа: товар с остатком ниже порога попадает в low-stock.
+-- 0 — порог не задан.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_threshold INT NOT NULL DEFAULT 0
+    CHECK (reorder_threshold >= 0);
+CREATE INDEX IF NOT EXISTS idx_products_low_stock ON products(id)
+    WHERE reorder_threshold > 0 AND stock < reorder_threshold;
+
+-- Отправленные оповещения. Пока строка есть, повторное оповещение по товару
+-- не отправляется; строка удаляется, когда остаток снова дорос до порога.
+CREATE TABLE IF NOT EXISTS low_stock_alerts (
+    product_id INT PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    stock INT NOT NULL,
+    threshold INT NOT NULL,
+    alerted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
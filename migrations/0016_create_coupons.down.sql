@@ -0,0 +1,4 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS unit_price;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_total;
+DROP TABLE IF EXISTS order_discounts;
+DROP TABLE IF EXISTS coupons;
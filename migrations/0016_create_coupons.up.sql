@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS coupons (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('percent', 'fixed', 'free_item')),
+    percent_off INT CHECK (percent_off BETWEEN 1 AND 100),
+    amount_off NUMERIC(12,2) CHECK (amount_off > 0),
+    free_product_id INT REFERENCES products(id) ON DELETE CASCADE,
+    free_variant_id INT REFERENCES product_variants(id) ON DELETE CASCADE,
+    free_quantity INT NOT NULL DEFAULT 1 CHECK (free_quantity > 0),
+    min_order NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (min_order >= 0),
+    starts_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    max_uses INT CHECK (max_uses > 0),                  -- NULL — без ограничения
+    max_uses_per_customer INT CHECK (max_uses_per_customer > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind <> 'percent' OR percent_off IS NOT NULL),
+    CHECK (kind <> 'fixed' OR amount_off IS NOT NULL),
+    CHECK (kind <> 'free_item' OR free_product_id IS NOT NULL)
+);
+-- Код вводят покупатели, поэтому сравнение без учёта регистра.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupons_code ON coupons(UPPER(code));
+
+-- Скидки, применённые к заказу. code и description копируются, чтобы история
+-- не менялась при правке или удалении купона.
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    coupon_id INT REFERENCES coupons(id) ON DELETE SET NULL,
+    code TEXT NOT NULL,
+    description TEXT NOT NULL,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_order_discounts_order_id ON order_discounts(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_discounts_coupon_id ON order_discounts(coupon_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_total NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+-- Цена позиции на момент заказа. Для старых заказов берём текущую цену.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS unit_price NUMERIC(12,2);
+UPDATE order_items oi
+SET unit_price = COALESCE(
+    (SELECT v.price FROM product_variants v WHERE v.id = oi.variant_id),
+    (SELECT p.price FROM products p WHERE p.id = oi.product_id))
+WHERE unit_price IS NULL;
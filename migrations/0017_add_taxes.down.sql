@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS order_tax_lines;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS prices_include_tax;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_region;
+DROP TABLE IF EXISTS tax_rates;
+DROP TABLE IF EXISTS tax_regions;
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class;
@@ -0,0 +1,44 @@
+-- Налоговый класс товара: standard — основная ставка, reduced — льготная
+-- (корма), exempt — без налога (ветеринарные препараты и услуги).
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_class TEXT NOT NULL DEFAULT 'standard'
+    CHECK (tax_class IN ('standard', 'reduced', 'exempt'));
+
+-- Налоговые регионы. prices_include_tax — цены каталога уже содержат налог
+-- (налог выделяется из суммы), иначе налог начисляется сверху.
+CREATE TABLE IF NOT EXISTS tax_regions (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    prices_include_tax BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- Ставки в базисных пунктах: 2000 = 20%. Нет строки — ставка 0.
+CREATE TABLE IF NOT EXISTS tax_rates (
+    region_code TEXT NOT NULL REFERENCES tax_regions(code) ON DELETE CASCADE,
+    tax_class TEXT NOT NULL CHECK (tax_class IN ('standard', 'reduced', 'exempt')),
+    rate_bp INT NOT NULL CHECK (rate_bp BETWEEN 0 AND 10000),
+    PRIMARY KEY (region_code, tax_class)
+);
+
+INSERT INTO tax_regions (code, name, prices_include_tax) VALUES ('RU', 'Россия', TRUE)
+ON CONFLICT (code) DO NOTHING;
+INSERT INTO tax_rates (region_code, tax_class, rate_bp) VALUES
+    ('RU', 'standard', 2000),
+    ('RU', 'reduced', 1000),
+    ('RU', 'exempt', 0)
+ON CONFLICT (region_code, tax_class) DO NOTHING;
+
+-- Налог заказа. Регион и режим копируются, чтобы правка ставок не меняла
+-- уже оформленные заказы. У старых заказов регион не задан и налог 0.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_region TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_total NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tax_class TEXT NOT NULL,
+    rate_bp INT NOT NULL,
+    taxable_amount NUMERIC(12,2) NOT NULL, -- сумма позиций класса за вычетом доли скидки
+    amount NUMERIC(12,2) NOT NULL CHECK (amount >= 0),
+    UNIQUE (order_id, tax_class)
+);
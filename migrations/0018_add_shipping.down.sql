@@ -0,0 +1,7 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_total;
+DROP TABLE IF EXISTS order_shipments;
+DROP TABLE IF EXISTS shipping_rates;
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS shipping_zones;
+DROP TABLE IF EXISTS addresses;
+ALTER TABLE products DROP COLUMN IF EXISTS weight_grams;
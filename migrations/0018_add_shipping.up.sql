@@ -0,0 +1,95 @@
+-- Вес товара для расчёта доставки. Варианты наследуют вес товара.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INT NOT NULL DEFAULT 0
+    CHECK (weight_grams >= 0);
+
+-- Адресная книга покупателя. У пользователя не больше одного адреса
+-- по умолчанию.
+CREATE TABLE IF NOT EXISTS addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label TEXT NOT NULL DEFAULT '',
+    recipient TEXT NOT NULL,
+    phone TEXT NOT NULL DEFAULT '',
+    country CHAR(2) NOT NULL,  -- ISO 3166-1 alpha-2
+    city TEXT NOT NULL,
+    postal_code TEXT NOT NULL DEFAULT '',
+    line1 TEXT NOT NULL,
+    line2 TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default ON addresses(user_id) WHERE is_default;
+
+-- Зоны доставки по странам назначения.
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    countries CHAR(2)[] NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- Тарифы способа доставки по зоне: берётся строка с наименьшим max_weight_grams,
+-- в который укладывается заказ (NULL — без ограничения веса). Если сумма
+-- заказа после скидки не меньше free_threshold, доставка бесплатна.
+CREATE TABLE IF NOT EXISTS shipping_rates (
+    id SERIAL PRIMARY KEY,
+    method_id INT NOT NULL REFERENCES shipping_methods(id) ON DELETE CASCADE,
+    zone_code TEXT NOT NULL REFERENCES shipping_zones(code) ON DELETE CASCADE,
+    max_weight_grams INT CHECK (max_weight_grams > 0),
+    price NUMERIC(12,2) NOT NULL CHECK (price >= 0),
+    free_threshold NUMERIC(12,2) CHECK (free_threshold > 0)
+);
+CREATE INDEX IF NOT EXISTS idx_shipping_rates_method_zone ON shipping_rates(method_id, zone_code);
+
+INSERT INTO shipping_zones (code, name, countries) VALUES
+    ('domestic', 'Россия', '{RU}'),
+    ('eaeu', 'ЕАЭС', '{BY,KZ,AM,KG}')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO shipping_methods (code, name) VALUES
+    ('post', 'Почта'),
+    ('courier', 'Курьер')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO shipping_rates (method_id, zone_code, max_weight_grams, price, free_threshold)
+SELECT m.id, r.zone_code, r.max_weight_grams, r.price, r.free_threshold
+FROM (VALUES
+    ('post', 'domestic', 1000, 300.00, 5000.00),
+    ('post', 'domestic', 5000, 500.00, 5000.00),
+    ('post', 'domestic', NULL, 900.00, NULL),
+    ('post', 'eaeu', 5000, 1500.00, NULL),
+    ('post', 'eaeu', NULL, 3000.00, NULL),
+    ('courier', 'domestic', 10000, 700.00, 10000.00),
+    ('courier', 'domestic', NULL, 1200.00, NULL)
+) AS r(method_code, zone_code, max_weight_grams, price, free_threshold)
+JOIN shipping_methods m ON m.code = r.method_code
+WHERE NOT EXISTS (SELECT 1 FROM shipping_rates);
+
+-- Доставка заказа. Адрес копируется, чтобы правка или удаление записи
+-- в адресной книге не меняли оформленный заказ.
+CREATE TABLE IF NOT EXISTS order_shipments (
+    order_id INT PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    address_id INT REFERENCES addresses(id) ON DELETE SET NULL,
+    method_code TEXT NOT NULL,
+    method_name TEXT NOT NULL,
+    zone_code TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    phone TEXT NOT NULL,
+    country CHAR(2) NOT NULL,
+    city TEXT NOT NULL,
+    postal_code TEXT NOT NULL,
+    line1 TEXT NOT NULL,
+    line2 TEXT NOT NULL,
+    weight_grams INT NOT NULL,
+    cost NUMERIC(12,2) NOT NULL CHECK (cost >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_total NUMERIC(12,2) NOT NULL DEFAULT 0;
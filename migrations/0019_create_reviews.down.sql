@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_products_rating;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_avg;
+DROP TABLE IF EXISTS reviews;
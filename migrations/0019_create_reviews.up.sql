@@ -0,0 +1,23 @@
+-- Отзывы покупателей. Один отзыв пользователя на товар; новый и изменённый
+-- отзыв ждёт модерации (pending).
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    moderation_comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_reviews_product_approved ON reviews(product_id, created_at DESC) WHERE status = 'approved';
+CREATE INDEX IF NOT EXISTS idx_reviews_pending ON reviews(created_at) WHERE status = 'pending';
+
+-- Сводный рейтинг по одобренным отзывам. Хранится в товаре, чтобы каталог
+-- сортировался по рейтингу с keyset-пагинацией.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_avg NUMERIC(3,2) NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_count INT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_products_rating ON products(rating_avg, id);
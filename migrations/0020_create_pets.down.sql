@@ -0,0 +1,5 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS pet_id;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_pet_weight_range;
+ALTER TABLE products DROP COLUMN IF EXISTS pet_max_weight_grams;
+ALTER TABLE products DROP COLUMN IF EXISTS pet_min_weight_grams;
+DROP TABLE IF EXISTS pets;
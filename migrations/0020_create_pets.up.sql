@@ -0,0 +1,23 @@
+-- Питомцы покупателя. Вид и вес используются для подбора товаров в каталоге.
+CREATE TABLE IF NOT EXISTS pets (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    species_id INT NOT NULL REFERENCES species(id),
+    breed TEXT NOT NULL DEFAULT '',
+    birth_date DATE,
+    weight_grams INT CHECK (weight_grams > 0),
+    allergies TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_pets_user_id ON pets(user_id);
+
+-- Для питомцев какого веса подходит товар (корм для крупных пород,
+-- переноска до 8 кг). NULL — без ограничения.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS pet_min_weight_grams INT CHECK (pet_min_weight_grams > 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS pet_max_weight_grams INT CHECK (pet_max_weight_grams > 0);
+ALTER TABLE products ADD CONSTRAINT products_pet_weight_range
+    CHECK (pet_min_weight_grams IS NULL OR pet_max_weight_grams IS NULL OR pet_min_weight_grams <= pet_max_weight_grams);
+
+-- Для какого питомца оформлен заказ.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pet_id INT REFERENCES pets(id) ON DELETE SET NULL;
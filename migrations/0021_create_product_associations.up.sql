@@ -0,0 +1,15 @@
+-- Пары товаров, которые покупают в одном заказе. Таблицу целиком
+-- пересчитывает фоновая задача (internal/recommendation) по оплаченным
+-- заказам; support — число заказов с обоими товарами,
+-- confidence = P(related | product), lift = confidence / P(related).
+CREATE TABLE IF NOT EXISTS product_associations (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    support INT NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL,
+    lift DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, related_id),
+    CHECK (product_id <> related_id)
+);
+CREATE INDEX IF NOT EXISTS idx_product_associations_related ON product_associations(related_id);
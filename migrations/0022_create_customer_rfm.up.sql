@@ -0,0 +1,19 @@
+-- RFM-оценки покупателей. Таблицу целиком пересчитывает фоновая задача
+-- (internal/segmentation) по оплаченным заказам; покупатели без оплаченных
+-- заказов в неё не попадают.
+CREATE TABLE IF NOT EXISTS customer_rfm (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    recency_days INT NOT NULL,
+    frequency INT NOT NULL,
+    monetary NUMERIC(12, 2) NOT NULL,
+    r_score SMALLINT NOT NULL CHECK (r_score BETWEEN 1 AND 5),
+    f_score SMALLINT NOT NULL CHECK (f_score BETWEEN 1 AND 5),
+    m_score SMALLINT NOT NULL CHECK (m_score BETWEEN 1 AND 5),
+    lifetime_value NUMERIC(12, 2) NOT NULL,
+    order_count INT NOT NULL,
+    first_order_at TIMESTAMP NOT NULL,
+    last_order_at TIMESTAMP NOT NULL,
+    segment TEXT NOT NULL CHECK (segment IN ('champion', 'at-risk', 'lost', 'new', 'regular')),
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_customer_rfm_segment ON customer_rfm(segment, lifetime_value DESC);
@@ -0,0 +1,4 @@
+-- Артикул товара — естественный ключ для массового импорта каталога.
+-- У старых товаров артикула нет, NULL не мешает уникальности.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku);
@@ -0,0 +1,2 @@
+-- Отмена пустых заказов необратима: снова открывать их незачем.
+SELECT 1;
@@ -0,0 +1,10 @@
+-- POST /orders создавал заказы без позиций, резервов и платежа. Оплатить
+-- их нельзя, а sweeper их не видит: отменяем оставшиеся.
+WITH cancelled AS (
+    UPDATE orders o SET status = 'cancelled'
+    WHERE o.status = 'pending_payment'
+      AND NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id)
+    RETURNING o.id
+)
+INSERT INTO order_status_history (order_id, from_status, to_status, comment)
+SELECT id, 'pending_payment', 'cancelled', 'empty order' FROM cancelled;
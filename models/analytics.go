@@ -0,0 +1,128 @@
+package models
+
+import "time"
+
+// Granularity — шаг временного ряда в отчётах.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week" // неделя начинается с понедельника
+	GranularityMonth Granularity = "month"
+)
+
+func (g Granularity) Valid() bool {
+	switch g {
+	case GranularityDay, GranularityWeek, GranularityMonth:
+		return true
+	}
+	return false
+}
+
+// RevenueQuery задаёт отчёт о выручке. From и To — календарные дни
+// в часовом поясе Timezone, оба включительно.
+type RevenueQuery struct {
+	From        time.Time
+	To          time.Time
+	Granularity Granularity
+	Timezone    string // имя из базы IANA, например Europe/Moscow
+}
+
+// RevenueBucket — показатели оплаченных заказов за один период.
+type RevenueBucket struct {
+	Start             string `json:"start"` // первый день периода, YYYY-MM-DD
+	GrossRevenue      Money  `json:"gross_revenue"`
+	OrderCount        int    `json:"order_count"`
+	AverageOrderValue Money  `json:"average_order_value"`
+	UnitsSold         int    `json:"units_sold"`
+}
+
+// RevenueReport — временной ряд выручки. Периоды без заказов присутствуют
+// с нулями, Total — итог за весь интервал.
+type RevenueReport struct {
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	Granularity Granularity     `json:"granularity"`
+	Timezone    string          `json:"timezone"`
+	Buckets     []RevenueBucket `json:"buckets"`
+	Total       RevenueBucket   `json:"total"`
+}
+
+// AverageOrderValue возвращает средний чек, 0 — если заказов нет.
+func AverageOrderValue(revenue Money, orders int) Money {
+	if orders == 0 {
+		return 0
+	}
+	return revenue.MulFrac(1, int64(orders))
+}
+
+// PopularityMetric — по чему ранжируются товары в отчёте о популярности.
+type PopularityMetric string
+
+const (
+	PopularityUnits   PopularityMetric = "units"
+	PopularityRevenue PopularityMetric = "revenue"
+)
+
+func (m PopularityMetric) Valid() bool {
+	return m == PopularityUnits || m == PopularityRevenue
+}
+
+// PopularQuery задаёт отчёт о самых продаваемых товарах. Если From
+// не задан, отчёт строится за всё время и без сравнения с прошлым периодом.
+type PopularQuery struct {
+	From     time.Time // день в часовом поясе Timezone, включительно
+	To       time.Time // включительно
+	Timezone string
+	Limit    int
+	// CategoryID отбирает товары категории и всех её подкатегорий.
+	CategoryID *int
+	Metric     PopularityMetric
+}
+
+// CustomerSegment — сегмент покупателя по RFM-оценкам.
+type CustomerSegment string
+
+const (
+	SegmentNew      CustomerSegment = "new"      // первая покупка недавно
+	SegmentChampion CustomerSegment = "champion" // покупает часто и недавно
+	SegmentAtRisk   CustomerSegment = "at-risk"  // покупал часто, но давно
+	SegmentLost     CustomerSegment = "lost"     // покупал редко и давно
+	SegmentRegular  CustomerSegment = "regular"  // все остальные
+)
+
+func (s CustomerSegment) Valid() bool {
+	switch s {
+	case SegmentNew, SegmentChampion, SegmentAtRisk, SegmentLost, SegmentRegular:
+		return true
+	}
+	return false
+}
+
+// CustomerRFM — RFM-оценки покупателя на момент ComputedAt. Frequency
+// и Monetary считаются за окно анализа, LifetimeValue и OrderCount — за всё
+// время. Оценки 1–5 — квинтили среди всех покупателей, 5 — лучшие.
+type CustomerRFM struct {
+	UserID         int             `json:"user_id"`
+	Email          string          `json:"email,omitempty"`
+	Name           string          `json:"name,omitempty"`
+	RecencyDays    int             `json:"recency_days"` // дней с последнего оплаченного заказа
+	Frequency      int             `json:"frequency"`
+	Monetary       Money           `json:"monetary"`
+	RecencyScore   int             `json:"r_score"`
+	FrequencyScore int             `json:"f_score"`
+	MonetaryScore  int             `json:"m_score"`
+	LifetimeValue  Money           `json:"lifetime_value"`
+	OrderCount     int             `json:"order_count"`
+	FirstOrderAt   time.Time       `json:"first_order_at"`
+	LastOrderAt    time.Time       `json:"last_order_at"`
+	Segment        CustomerSegment `json:"segment"`
+	ComputedAt     time.Time       `json:"computed_at"`
+}
+
+// CustomerRFMQuery — страница списка покупателей, самые ценные первыми.
+type CustomerRFMQuery struct {
+	Segment CustomerSegment // пусто — все сегменты
+	Limit   int
+	Offset  int
+}
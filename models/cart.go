@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+type CartStatus string
+
+const (
+	CartOpen       CartStatus = "open"
+	CartCheckedOut CartStatus = "checked_out"
+)
+
+// Cart — серверная корзина. Корзина без UserID анонимная: доступ к ней
+// даёт знание её ID, который клиент хранит как токен корзины. При
+// оформлении анонимная корзина закрепляется за покупателем.
+type Cart struct {
+	ID        string     `json:"id"`
+	UserID    *int       `json:"user_id,omitempty"`
+	Status    CartStatus `json:"status"`
+	OrderID   *int       `json:"order_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type CartItem struct {
+	ID        int    `json:"id"`
+	CartID    string `json:"cart_id"`
+	ProductID int    `json:"product_id"`
+	VariantID *int   `json:"variant_id,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+// CartWarning сообщает, что позицию корзины нельзя оформить в текущем виде.
+type CartWarning string
+
+const (
+	CartWarningOutOfStock        CartWarning = "out_of_stock"
+	CartWarningInsufficientStock CartWarning = "insufficient_stock"
+)
+
+// CartLine — позиция корзины с актуальной ценой и остатком на момент
+// просмотра. Цена в корзине не фиксируется: заказ получит цену на момент
+// оформления.
+type CartLine struct {
+	CartItem
+	ProductName string       `json:"product_name"`
+	SKU         string       `json:"sku,omitempty"`
+	UnitPrice   Money        `json:"unit_price"`
+	LineTotal   Money        `json:"line_total"`
+	Available   int          `json:"available"`
+	Warning     *CartWarning `json:"warning,omitempty"`
+}
+
+type CartView struct {
+	Cart
+	Lines []CartLine `json:"lines"`
+	Total Money      `json:"total"`
+	// CanCheckout == false, если корзина пуста, закрыта или у какой-то
+	// позиции есть предупреждение.
+	CanCheckout bool `json:"can_checkout"`
+}
+
+// NewCartView считает суммы и предупреждения по позициям корзины.
+func NewCartView(cart Cart, lines []CartLine) CartView {
+	view := CartView{Cart: cart, Lines: lines}
+	if view.Lines == nil {
+		view.Lines = []CartLine{}
+	}
+
+	view.CanCheckout = cart.Status == CartOpen && len(lines) > 0
+	for i := range view.Lines {
+		line := &view.Lines[i]
+		line.LineTotal = line.UnitPrice.Mul(line.Quantity)
+		view.Total += line.LineTotal
+
+		var warning CartWarning
+		switch {
+		case line.Available <= 0:
+			warning = CartWarningOutOfStock
+		case line.Available < line.Quantity:
+			warning = CartWarningInsufficientStock
+		default:
+			continue
+		}
+		line.Warning = &warning
+		view.CanCheckout = false
+	}
+
+	return view
+}
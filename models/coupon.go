@@ -0,0 +1,145 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type CouponKind string
+
+const (
+	// CouponPercent — скидка PercentOff процентов от суммы позиций.
+	CouponPercent CouponKind = "percent"
+	// CouponFixed — скидка AmountOff, но не больше суммы позиций.
+	CouponFixed CouponKind = "fixed"
+	// CouponFreeItem — в заказ бесплатно добавляется FreeQuantity штук
+	// товара FreeProductID (варианта FreeVariantID).
+	CouponFreeItem CouponKind = "free_item"
+)
+
+var ErrInvalidCoupon = errors.New("invalid coupon")
+
+// Coupon — промокод. Ограничения по датам, числу использований и минимальной
+// сумме проверяются при оформлении заказа; nil означает «без ограничения».
+// Использованием считается неотменённый заказ с этим купоном.
+type Coupon struct {
+	ID                 int        `json:"id"`
+	Code               string     `json:"code"`
+	Kind               CouponKind `json:"kind"`
+	PercentOff         int        `json:"percent_off,omitempty"`
+	AmountOff          Money      `json:"amount_off,omitempty"`
+	FreeProductID      *int       `json:"free_product_id,omitempty"`
+	FreeVariantID      *int       `json:"free_variant_id,omitempty"`
+	FreeQuantity       int        `json:"free_quantity,omitempty"`
+	MinOrder           Money      `json:"min_order"`
+	StartsAt           *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	MaxUses            *int       `json:"max_uses,omitempty"`
+	MaxUsesPerCustomer *int       `json:"max_uses_per_customer,omitempty"`
+	Active             bool       `json:"active"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Normalize приводит код к верхнему регистру и заполняет значения
+// по умолчанию.
+func (c *Coupon) Normalize() {
+	c.Code = NormalizeCouponCode(c.Code)
+	if c.Kind == CouponFreeItem && c.FreeQuantity == 0 {
+		c.FreeQuantity = 1
+	}
+}
+
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate проверяет, что поля купона согласованы с его видом.
+func (c Coupon) Validate() error {
+	if c.Code == "" {
+		return fmt.Errorf("%w: code is required", ErrInvalidCoupon)
+	}
+	if c.MinOrder < 0 {
+		return fmt.Errorf("%w: min_order must not be negative", ErrInvalidCoupon)
+	}
+	if c.StartsAt != nil && c.ExpiresAt != nil && !c.ExpiresAt.After(*c.StartsAt) {
+		return fmt.Errorf("%w: expires_at must be after starts_at", ErrInvalidCoupon)
+	}
+	if c.MaxUses != nil && *c.MaxUses <= 0 || c.MaxUsesPerCustomer != nil && *c.MaxUsesPerCustomer <= 0 {
+		return fmt.Errorf("%w: usage limits must be positive", ErrInvalidCoupon)
+	}
+
+	switch c.Kind {
+	case CouponPercent:
+		if c.PercentOff < 1 || c.PercentOff > 100 {
+			return fmt.Errorf("%w: percent_off must be between 1 and 100", ErrInvalidCoupon)
+		}
+	case CouponFixed:
+		if c.AmountOff <= 0 {
+			return fmt.Errorf("%w: amount_off must be positive", ErrInvalidCoupon)
+		}
+	case CouponFreeItem:
+		if c.FreeProductID == nil {
+			return fmt.Errorf("%w: free_product_id is required", ErrInvalidCoupon)
+		}
+		if c.FreeQuantity <= 0 {
+			return fmt.Errorf("%w: free_quantity must be positive", ErrInvalidCoupon)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidCoupon, c.Kind)
+	}
+
+	return nil
+}
+
+// Discount возвращает скидку процентного или фиксированного купона
+// на сумму позиций subtotal. Скидка никогда не больше subtotal.
+// Для free_item скидку считает оформление заказа по цене подарка.
+func (c Coupon) Discount(subtotal Money) Money {
+	var d Money
+	switch c.Kind {
+	case CouponPercent:
+		d = subtotal.MulFrac(int64(c.PercentOff), 100)
+	case CouponFixed:
+		d = c.AmountOff
+	}
+	return min(d, subtotal)
+}
+
+// Description — человекочитаемое описание скидки для строки заказа.
+func (c Coupon) Description() string {
+	switch c.Kind {
+	case CouponPercent:
+		return fmt.Sprintf("%d%% off", c.PercentOff)
+	case CouponFixed:
+		return fmt.Sprintf("%s off", c.AmountOff)
+	case CouponFreeItem:
+		return fmt.Sprintf("free item: product %d x%d", *c.FreeProductID, c.FreeQuantity)
+	}
+	return string(c.Kind)
+}
+
+// OrderDiscount — скидка, применённая к заказу.
+type OrderDiscount struct {
+	ID          int       `json:"id"`
+	OrderID     int       `json:"order_id"`
+	CouponID    *int      `json:"coupon_id,omitempty"`
+	Code        string    `json:"code"`
+	Description string    `json:"description"`
+	Amount      Money     `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OrderDraft — состав нового заказа: позиции, необязательный промокод,
+// налоговый регион (пустой — регион магазина по умолчанию) и доставка.
+// Без AddressID заказ оформляется без доставки (самовывоз). PetID — для
+// какого питомца покупателя заказ.
+type OrderDraft struct {
+	Items          []OrderItem `json:"items"`
+	CouponCode     string      `json:"coupon_code,omitempty"`
+	TaxRegion      string      `json:"tax_region,omitempty"`
+	AddressID      *int        `json:"address_id,omitempty"`
+	ShippingMethod string      `json:"shipping_method,omitempty"`
+	PetID          *int        `json:"pet_id,omitempty"`
+}
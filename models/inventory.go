@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// InventoryReason — причина движения остатка.
+type InventoryReason string
+
+const (
+	InventorySale         InventoryReason = "sale"
+	InventoryCancellation InventoryReason = "cancellation"
+	InventoryAdjustment   InventoryReason = "adjustment"
+	InventoryRestock      InventoryReason = "restock"
+	InventoryReturn       InventoryReason = "return"
+)
+
+// Manual сообщает, можно ли записать движение с такой причиной вручную.
+// sale и cancellation пишет только оформление и отмена заказов.
+func (r InventoryReason) Manual() bool {
+	switch r {
+	case InventoryAdjustment, InventoryRestock, InventoryReturn:
+		return true
+	}
+	return false
+}
+
+// InventoryMovement — строка журнала движений остатков. VariantID == nil —
+// движение остатка самого товара, ActorID == nil — изменение системой.
+type InventoryMovement struct {
+	ID          int64           `json:"id"`
+	ProductID   int             `json:"product_id"`
+	VariantID   *int            `json:"variant_id,omitempty"`
+	Delta       int             `json:"delta"`
+	Reason      InventoryReason `json:"reason"`
+	ReferenceID *int            `json:"reference_id,omitempty"`
+	ActorID     *int            `json:"actor_id,omitempty"`
+	Comment     string          `json:"comment,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// InventoryDiscrepancy — товар или вариант, чей остаток расходится
+// с суммой движений в журнале.
+type InventoryDiscrepancy struct {
+	ProductID int  `json:"product_id"`
+	VariantID *int `json:"variant_id,omitempty"`
+	Stock     int  `json:"stock"`
+	LedgerSum int  `json:"ledger_sum"`
+}
+
+// LowStockProduct — товар, чей остаток опустился ниже порога дозаказа.
+// AlertedAt != nil, если оповещение уже отправлено: повторно оно уйдёт
+// только после пополнения остатка до порога.
+type LowStockProduct struct {
+	ProductID        int        `json:"product_id"`
+	Name             string     `json:"name"`
+	Stock            int        `json:"stock"`
+	ReorderThreshold int        `json:"reorder_threshold"`
+	AlertedAt        *time.Time `json:"alerted_at,omitempty"`
+}
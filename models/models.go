@@ -3,10 +3,140 @@ package models
 import "time"
 
 type Product struct {
-	ID    int
-	Name  string
-	Price float64
-	Stock int // количество на складе
+	ID               int
+	SKU              string // артикул, уникален; пустой — не задан
+	Name             string
+	Price            Money
+	Stock            int      // количество на складе
+	Available        int      // на складе минус активные резервы неоплаченных заказов
+	ReorderThreshold int      // порог дозаказа, 0 — не задан; см. GetLowStockProducts
+	TaxClass         TaxClass // пустой при создании — standard
+	WeightGrams      int      // вес для расчёта доставки, общий для всех вариантов
+	Rating           float64  // средняя оценка одобренных отзывов, 0 — отзывов нет
+	ReviewCount      int
+	// Для питомцев какого веса подходит товар, nil — без ограничения.
+	PetMinWeightGrams *int
+	PetMaxWeightGrams *int
+	CategoryID        *int
+	Species           []Species // для каких животных подходит товар
+	CreatedAt         time.Time
+}
+
+// Species — код вида животного из таблицы species.
+type Species string
+
+const (
+	SpeciesDog         Species = "dog"
+	SpeciesCat         Species = "cat"
+	SpeciesBird        Species = "bird"
+	SpeciesFish        Species = "fish"
+	SpeciesReptile     Species = "reptile"
+	SpeciesSmallMammal Species = "small_mammal"
+)
+
+// ProductVariant — конкретный SKU товара (например, мешок корма 2 кг)
+// со своей ценой, остатком и атрибутами вроде size, flavour, colour.
+type ProductVariant struct {
+	ID         int               `json:"id"`
+	ProductID  int               `json:"product_id"`
+	SKU        string            `json:"sku"`
+	Price      Money             `json:"price"`
+	Stock      int               `json:"stock"`
+	Available  int               `json:"available"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// ProductDetails — карточка товара с матрицей вариантов. Options содержит
+// все значения каждого атрибута, встречающиеся среди вариантов.
+type ProductDetails struct {
+	Product
+	Variants []ProductVariant    `json:"variants"`
+	Options  map[string][]string `json:"options"`
+}
+
+func NewProductDetails(p Product, variants []ProductVariant) ProductDetails {
+	options := map[string][]string{}
+	seen := map[string]bool{}
+	for _, v := range variants {
+		for name, value := range v.Attributes {
+			if key := name + "\x00" + value; !seen[key] {
+				seen[key] = true
+				options[name] = append(options[name], value)
+			}
+		}
+	}
+
+	if variants == nil {
+		variants = []ProductVariant{}
+	}
+
+	return ProductDetails{Product: p, Variants: variants, Options: options}
+}
+
+type SpeciesInfo struct {
+	ID   int     `json:"id"`
+	Code Species `json:"code"`
+	Name string  `json:"name"`
+}
+
+type Category struct {
+	ID       int    `json:"id"`
+	ParentID *int   `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+}
+
+// Допустимые значения ProductQuery.Sort.
+const (
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortNameAsc   = "name_asc"
+	SortNameDesc  = "name_desc"
+	SortNewest    = "newest"
+	SortRating    = "rating"
+)
+
+// ProductQuery задаёт страницу каталога: фильтры, сортировку и курсор,
+// полученный из NextCursor предыдущей страницы.
+type ProductQuery struct {
+	Limit    int
+	Cursor   string
+	MinPrice *Money
+	MaxPrice *Money
+	InStock  bool
+	// CategoryID отбирает товары категории и всех её подкатегорий.
+	CategoryID *int
+	Species    Species
+	// PetSpecies отбирает товары, подходящие питомцу этого вида: вид указан
+	// у товара или виды у товара не указаны вовсе.
+	PetSpecies Species
+	// PetWeightGrams отбирает товары, подходящие питомцу такого веса.
+	PetWeightGrams *int
+	Sort           string // пусто — по id
+}
+
+type ProductPage struct {
+	Items      []Product `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Total      int       `json:"total"`
+}
+
+type ProductSearchQuery struct {
+	Text   string
+	Limit  int
+	Offset int
+}
+
+type ProductSearchResult struct {
+	Product Product `json:"product"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"` // название с найденными словами в <mark></mark>
+}
+
+type ProductSearchPage struct {
+	Items []ProductSearchResult `json:"items"`
+	Total int                   `json:"total"`
+	Fuzzy bool                  `json:"fuzzy"` // true, если сработал поиск по триграммам
 }
 
 type Customer struct {
@@ -16,25 +146,40 @@ type Customer struct {
 }
 
 type Order struct {
-	ID         int
-	CustomerID int
-	CreatedAt  time.Time
+	ID               int
+	CustomerID       int
+	Status           OrderStatus
+	TotalPrice       Money // к оплате: со скидкой и налогом
+	DiscountTotal    Money
+	TaxTotal         Money
+	ShippingTotal    Money
+	PetID            *int   // для какого питомца оформлен заказ
+	TaxRegion        string // пустой у заказов, оформленных до учёта налогов
+	PricesIncludeTax bool   // налог уже входит в цены позиций
+	CreatedAt        time.Time
 }
 
 type OrderItem struct {
-    ID        int `json:"id"`
-    OrderID   int `json:"order_id"`
-    ProductID int `json:"product_id"`
-    Quantity  int `json:"quantity"`
+    ID        int  `json:"id"`
+    OrderID   int  `json:"order_id"`
+    ProductID int  `json:"product_id"`
+    VariantID *int `json:"variant_id,omitempty"` // обязателен для товаров с вариантами
+    Quantity  int  `json:"quantity"`
+    UnitPrice Money `json:"unit_price"` // цена на момент заказа, во входящих запросах игнорируется
 }
 
 type OrderDetail struct {
 	OrderID int `json:"order_id"`
 	ProductName string `json:"product_name"`
 	ProductID int `json:"product_id"`
-	Price float64 `json:"price"`
+	Price Money `json:"price"`
 	Quantity int `json:"quantity"`
-	TotalPrice float64 `json:"total_price"`
+	TotalPrice Money `json:"total_price"`
+	Discount Money `json:"discount"` // скидка по промокоду на весь заказ, уже учтена в TotalPrice
+	Tax Money `json:"tax"` // налог на весь заказ; начислен сверху, если PricesIncludeTax == false
+	PricesIncludeTax bool `json:"prices_include_tax"`
+	Shipping Money `json:"shipping"` // стоимость доставки заказа, уже учтена в TotalPrice
+	PetID *int `json:"pet_id,omitempty"`
 	Status string `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 	TransactionStatus string `json:"transaction_status"`
@@ -44,10 +189,55 @@ type PopularProduct struct {
 	ID int `json:"id"`
 	Name string `json:"name"`
 	TotalSold int `json:"total_sold"`
+	Revenue Money `json:"revenue"`
+	Rank int `json:"rank"`
+	// Место за предыдущий период такой же длины; nil — тогда товар
+	// не продавался или отчёт построен за всё время.
+	PreviousRank *int `json:"previous_rank,omitempty"`
+	RankChange *int `json:"rank_change,omitempty"` // PreviousRank - Rank, больше нуля — товар поднялся
+}
+
+// CategoryPopularProducts — самые продаваемые товары одной категории.
+// CategoryID == nil означает товары без категории.
+type CategoryPopularProducts struct {
+	CategoryID   *int             `json:"category_id"`
+	CategoryName string           `json:"category_name"`
+	Products     []PopularProduct `json:"products"`
+}
+
+// Role определяет набор прав пользователя, см. internal/lib/auth/policy.go.
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleStaff    Role = "staff"
+	RoleCustomer Role = "customer"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleStaff, RoleCustomer:
+		return true
+	}
+	return false
 }
 
 type User struct {
-	ID    int
-	Name  string
-	Email string
+	ID           int
+	Name         string
+	Email        string
+	Role         Role
+	Password     string `json:"password,omitempty"` // только во входящих запросах
+	PasswordHash string `json:"-"`
+}
+
+// IdempotencyRecord — сохранённый результат запроса с заголовком Idempotency-Key.
+// Completed == false, пока первый запрос с этим ключом ещё выполняется.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
 }
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money — денежная сумма в минимальных единицах валюты магазина (копейках).
+// Магазин работает в одной валюте, поэтому код валюты не хранится.
+//
+// Правила округления:
+//   - суммы в базе хранятся как NUMERIC(12,2), при разборе строки или JSON
+//     больше двух знаков после точки — ошибка, а не молчаливое округление;
+//   - сложение и умножение на количество точные, без округления;
+//   - доли суммы (проценты скидок и налогов) считает MulFrac, округляя
+//     половину копейки от нуля: 0.005 -> 0.01, -0.005 -> -0.01.
+//
+// Поэтому total заказа, сумма позиций и transactions.amount всегда совпадают
+// до копейки.
+type Money int64
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// ParseMoney разбирает десятичную запись вида "12", "12.5" или "-12.50".
+// Допускается один ведущий минус; целая и дробная части — только ASCII-цифры.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	digits, neg := strings.CutPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if !isDigits(whole) || len(frac) > 2 || (frac != "" && !isDigits(frac)) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > (math.MaxInt64-99)/100 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+
+	m := Money(units*100 + cents)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+// isDigits сообщает, что s непустая и состоит только из цифр 0-9.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String возвращает сумму с двумя знаками после точки: "12.50".
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// Mul умножает сумму на целое количество.
+func (m Money) Mul(qty int) Money {
+	return m * Money(qty)
+}
+
+// MulFrac возвращает m * num / den с округлением половины от нуля.
+func (m Money) MulFrac(num, den int64) Money {
+	p := int64(m) * num
+	q, r := p/den, p%den
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= abs64(den) {
+		if (p < 0) != (den < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money(q)
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// MarshalJSON пишет сумму JSON-числом с двумя знаками: 12.50.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON принимает число или строку и разбирает их как десятичный
+// текст, не проходя через float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan читает NUMERIC из базы. pgx и database/sql передают его строкой.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case string:
+		return m.scanString(v)
+	case []byte:
+		return m.scanString(string(v))
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	}
+	return fmt.Errorf("%w: cannot scan %T", ErrInvalidMoney, src)
+}
+
+// scanString отбрасывает незначащие нули, которые база может вернуть для
+// NUMERIC с большим масштабом ("19.990"), но не округляет значащие цифры.
+func (m *Money) scanString(s string) error {
+	if whole, frac, ok := strings.Cut(s, "."); ok && len(frac) > 2 {
+		s = whole + "." + strings.TrimRight(frac, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value передаёт сумму в базу десятичной строкой.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "12", want: 1200},
+		{in: "12.5", want: 1250},
+		{in: "12.50", want: 1250},
+		{in: "12.", want: 1200},
+		{in: "0.05", want: 5},
+		{in: "-12.50", want: -1250},
+		{in: " 7.01 ", want: 701},
+		{in: "007.10", want: 710},
+
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "--5", wantErr: true},
+		{in: "-+5", wantErr: true},
+		{in: "+5", wantErr: true},
+		{in: "1.+5", wantErr: true},
+		{in: "1.-5", wantErr: true},
+		{in: "1.5-", wantErr: true},
+		{in: "1. 5", wantErr: true},
+		{in: "1_000", wantErr: true},
+		{in: "1.005", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "١٢", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		{in: "92233720368547758.07", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidMoney) {
+				t.Errorf("ParseMoney(%q) = %v, %v; want ErrInvalidMoney", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// roundTripDB имитирует запись суммы в NUMERIC(12,2) и чтение обратно.
+func roundTripDB(t *testing.T, m Money) Money {
+	t.Helper()
+
+	v, err := m.Value()
+	if err != nil {
+		t.Fatalf("Value(%d): %v", m, err)
+	}
+	var got Money
+	if err := got.Scan(v); err != nil {
+		t.Fatalf("Scan(%v): %v", v, err)
+	}
+	return got
+}
+
+// TestOrderTotalReconcilesWithTransactions проверяет на случайных заказах,
+// что total_price, собранный из позиций, скидки, налога и доставки так же,
+// как при оформлении, совпадает с суммой платежа в transactions.amount после
+// записи в базу и чтения обратно, а строка отмены обнуляет сумму платежей.
+func TestOrderTotalReconcilesWithTransactions(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for i := 0; i < 5000; i++ {
+		classTotals := map[TaxClass]Money{}
+		var itemsTotal Money
+		for n := 1 + rng.IntN(6); n > 0; n-- {
+			price := Money(1 + rng.IntN(500000))
+			line := price.Mul(1 + rng.IntN(10))
+			class := TaxClasses[rng.IntN(len(TaxClasses))]
+			classTotals[class] += line
+			itemsTotal += line
+		}
+
+		discount := Money(0)
+		if rng.IntN(2) == 0 {
+			discount = itemsTotal.MulFrac(int64(1+rng.IntN(100)), 100)
+		}
+
+		region := TaxRegion{
+			Code:             "R",
+			Name:             "Region",
+			PricesIncludeTax: rng.IntN(2) == 0,
+			Rates:            map[TaxClass]int{TaxStandard: rng.IntN(3000), TaxReduced: rng.IntN(1500)},
+		}
+		taxLines := CalculateTax(region, classTotals, discount)
+
+		var tax, taxable Money
+		for _, line := range taxLines {
+			tax += line.Amount
+			taxable += line.TaxableAmount
+		}
+		if taxable != itemsTotal-discount {
+			t.Fatalf("case %d: taxable %v != items %v - discount %v", i, taxable, itemsTotal, discount)
+		}
+
+		total := itemsTotal - discount
+		if !region.PricesIncludeTax {
+			total += tax
+		}
+		total += Money(rng.IntN(100000)) // доставка
+
+		payment := roundTripDB(t, total)
+		if payment != total {
+			t.Fatalf("case %d: transactions.amount %v != total_price %v", i, payment, total)
+		}
+
+		reversal := roundTripDB(t, -payment)
+		if payment+reversal != 0 {
+			t.Fatalf("case %d: payment %v and reversal %v do not cancel out", i, payment, reversal)
+		}
+
+		var fromJSON Money
+		data, _ := json.Marshal(total)
+		if err := json.Unmarshal(data, &fromJSON); err != nil || fromJSON != total {
+			t.Fatalf("case %d: JSON round trip of %v = %v, %v", i, total, fromJSON, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OrderStatus — состояние заказа. Все допустимые переходы перечислены
+// в orderTransitions, менять статус в обход CanTransitionTo нельзя.
+type OrderStatus string
+
+const (
+	OrderPendingPayment OrderStatus = "pending_payment"
+	OrderPaid           OrderStatus = "paid"
+	OrderPacked         OrderStatus = "packed"
+	OrderShipped        OrderStatus = "shipped"
+	OrderDelivered      OrderStatus = "delivered"
+	OrderCancelled      OrderStatus = "cancelled"
+	OrderRefunded       OrderStatus = "refunded"
+)
+
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPendingPayment: {OrderPaid, OrderCancelled},
+	OrderPaid:           {OrderPacked, OrderCancelled, OrderRefunded},
+	OrderPacked:         {OrderShipped, OrderCancelled},
+	OrderShipped:        {OrderDelivered},
+	OrderDelivered:      {OrderRefunded},
+	OrderCancelled:      {},
+	OrderRefunded:       {},
+}
+
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransition возвращает ErrInvalidTransition с описанием, если переход
+// из s в next запрещён.
+func (s OrderStatus) CheckTransition(next OrderStatus) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
+
+// OrderStatusChange — запись в истории статусов заказа. From == nil для
+// создания заказа, ChangedBy == nil для изменений, сделанных системой.
+type OrderStatusChange struct {
+	ID        int          `json:"id"`
+	OrderID   int          `json:"order_id"`
+	From      *OrderStatus `json:"from"`
+	To        OrderStatus  `json:"to"`
+	ChangedBy *int         `json:"changed_by"`
+	Comment   string       `json:"comment,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
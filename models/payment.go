@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// PaymentStatus — статус строки transactions.
+type PaymentStatus string
+
+const (
+	PaymentPending    PaymentStatus = "pending"    // ждём ответа платёжного шлюза
+	PaymentAuthorized PaymentStatus = "authorized" // деньги заблокированы, но не списаны
+	PaymentCaptured   PaymentStatus = "captured"   // деньги списаны
+	PaymentDeclined   PaymentStatus = "declined"
+	PaymentFailed     PaymentStatus = "failed" // шлюз не ответил
+	PaymentVoided     PaymentStatus = "voided"
+	PaymentRefunded   PaymentStatus = "refunded"
+	PaymentReversed   PaymentStatus = "reversed" // сторнирующая запись при отмене заказа
+)
+
+// Payment — строка transactions, через которую оплачивается заказ.
+type Payment struct {
+	ID               int           `json:"id"`
+	OrderID          int           `json:"order_id"`
+	Amount           Money         `json:"amount"`
+	Status           PaymentStatus `json:"status"`
+	GatewayReference string        `json:"gateway_reference,omitempty"`
+	CreatedAt        time.Time     `json:"created_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPet = errors.New("invalid pet")
+
+// Pet — питомец покупателя.
+type Pet struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"-"`
+	Name        string    `json:"name"`
+	Species     Species   `json:"species"`
+	Breed       string    `json:"breed,omitempty"`
+	BirthDate   *string   `json:"birth_date,omitempty"` // YYYY-MM-DD
+	WeightGrams *int      `json:"weight_grams,omitempty"`
+	Allergies   []string  `json:"allergies"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Normalize убирает лишние пробелы и пустые аллергии.
+func (p *Pet) Normalize() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Breed = strings.TrimSpace(p.Breed)
+	allergies := make([]string, 0, len(p.Allergies))
+	for _, a := range p.Allergies {
+		if a = strings.TrimSpace(a); a != "" {
+			allergies = append(allergies, a)
+		}
+	}
+	p.Allergies = allergies
+}
+
+func (p Pet) Validate() error {
+	if p.Name == "" || p.Species == "" {
+		return fmt.Errorf("%w: name and species are required", ErrInvalidPet)
+	}
+	if p.BirthDate != nil {
+		birth, err := time.Parse(time.DateOnly, *p.BirthDate)
+		if err != nil {
+			return fmt.Errorf("%w: birth_date must be YYYY-MM-DD", ErrInvalidPet)
+		}
+		if birth.After(time.Now()) {
+			return fmt.Errorf("%w: birth_date is in the future", ErrInvalidPet)
+		}
+	}
+	if p.WeightGrams != nil && *p.WeightGrams <= 0 {
+		return fmt.Errorf("%w: weight_grams must be positive", ErrInvalidPet)
+	}
+	return nil
+}
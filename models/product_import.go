@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidProduct = errors.New("invalid product")
+
+// Validate проверяет товар перед сохранением. Пустой TaxClass допустим:
+// при сохранении он становится standard.
+func (p Product) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProduct)
+	}
+	if p.Price < 0 {
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidProduct)
+	}
+	if p.Stock < 0 || p.ReorderThreshold < 0 {
+		return fmt.Errorf("%w: stock and reorder_threshold must not be negative", ErrInvalidProduct)
+	}
+	if p.TaxClass != "" && !p.TaxClass.Valid() {
+		return fmt.Errorf("%w: tax_class must be one of standard, reduced, exempt", ErrInvalidProduct)
+	}
+	if p.WeightGrams < 0 {
+		return fmt.Errorf("%w: weight_grams must not be negative", ErrInvalidProduct)
+	}
+	if p.PetMinWeightGrams != nil && *p.PetMinWeightGrams <= 0 || p.PetMaxWeightGrams != nil && *p.PetMaxWeightGrams <= 0 ||
+		p.PetMinWeightGrams != nil && p.PetMaxWeightGrams != nil && *p.PetMinWeightGrams > *p.PetMaxWeightGrams {
+		return fmt.Errorf("%w: pet_min_weight_grams and pet_max_weight_grams must be positive and ordered", ErrInvalidProduct)
+	}
+	return nil
+}
+
+// ImportFormat — формат файла массового импорта каталога.
+type ImportFormat string
+
+const (
+	ImportCSV    ImportFormat = "csv"    // первая строка — заголовок с именами колонок
+	ImportNDJSON ImportFormat = "ndjson" // по JSON-объекту на строку
+)
+
+func (f ImportFormat) Valid() bool {
+	return f == ImportCSV || f == ImportNDJSON
+}
+
+// ProductImportRow — строка файла импорта. Товар ищется по SKU; name, price
+// и stock обязательны. Необязательные поля, которых нет в строке (nil),
+// у существующего товара не меняются, у нового получают значения
+// по умолчанию.
+type ProductImportRow struct {
+	Line              int
+	SKU               string
+	Name              string
+	Price             Money
+	Stock             int
+	ReorderThreshold  *int
+	TaxClass          *TaxClass
+	WeightGrams       *int
+	PetMinWeightGrams *int
+	PetMaxWeightGrams *int
+	CategoryID        *int
+	Species           []Species // nil — не задано
+}
+
+// Apply переносит поля строки в товар p.
+func (row ProductImportRow) Apply(p *Product) {
+	p.SKU = row.SKU
+	p.Name = row.Name
+	p.Price = row.Price
+	p.Stock = row.Stock
+	if row.ReorderThreshold != nil {
+		p.ReorderThreshold = *row.ReorderThreshold
+	}
+	if row.TaxClass != nil {
+		p.TaxClass = *row.TaxClass
+	}
+	if row.WeightGrams != nil {
+		p.WeightGrams = *row.WeightGrams
+	}
+	if row.PetMinWeightGrams != nil {
+		p.PetMinWeightGrams = row.PetMinWeightGrams
+	}
+	if row.PetMaxWeightGrams != nil {
+		p.PetMaxWeightGrams = row.PetMaxWeightGrams
+	}
+	if row.CategoryID != nil {
+		p.CategoryID = row.CategoryID
+	}
+	if row.Species != nil {
+		p.Species = row.Species
+	}
+}
+
+// ImportAction — что импорт делает со строкой.
+type ImportAction string
+
+const (
+	ImportCreate ImportAction = "create"
+	ImportUpdate ImportAction = "update"
+	ImportReject ImportAction = "reject"
+)
+
+// ImportResult — итог по одной строке файла.
+type ImportResult struct {
+	Line      int          `json:"line"`
+	SKU       string       `json:"sku,omitempty"`
+	Action    ImportAction `json:"action"`
+	ProductID int          `json:"product_id,omitempty"` // у новых товаров — только если импорт применён
+	Error     string       `json:"error,omitempty"`
+}
+
+// ImportReport — итог импорта. Импорт применяется целиком или никак:
+// при dry-run или хотя бы одной отклонённой строке Applied == false
+// и каталог не меняется.
+type ImportReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Applied  bool           `json:"applied"`
+	Created  int            `json:"created"`
+	Updated  int            `json:"updated"`
+	Rejected int            `json:"rejected"`
+	Rows     []ImportResult `json:"rows"`
+}
+
+// Add учитывает итог строки в отчёте.
+func (r *ImportReport) Add(res ImportResult) {
+	switch res.Action {
+	case ImportCreate:
+		r.Created++
+	case ImportUpdate:
+		r.Updated++
+	case ImportReject:
+		r.Rejected++
+	}
+	r.Rows = append(r.Rows, res)
+}
@@ -0,0 +1,33 @@
+package models
+
+// RecommendationScore — метрика, по которой ранжируются рекомендации.
+type RecommendationScore string
+
+const (
+	// ScoreLift ставит выше товары, которые покупают вместе чаще, чем
+	// по отдельности; не выталкивает наверх просто популярные товары.
+	ScoreLift RecommendationScore = "lift"
+	// ScoreConfidence — доля заказов исходного товара, в которых есть
+	// рекомендуемый.
+	ScoreConfidence RecommendationScore = "confidence"
+)
+
+func (s RecommendationScore) Valid() bool {
+	return s == ScoreLift || s == ScoreConfidence
+}
+
+// RecommendationQuery — параметры выдачи рекомендаций.
+type RecommendationQuery struct {
+	Limit int
+	Score RecommendationScore
+}
+
+// Recommendation — товар, который покупают вместе с исходным. Для
+// персональных рекомендаций Support суммируется по всем купленным товарам,
+// а Confidence и Lift берутся лучшие.
+type Recommendation struct {
+	Product
+	Support    int // число заказов, в которых товары куплены вместе
+	Confidence float64
+	Lift       float64
+}
@@ -0,0 +1,15 @@
+package models
+
+// ReservationStatus — состояние резерва остатка под заказ, см. stock_reservations.
+type ReservationStatus string
+
+const (
+	// ReservationActive — остаток удерживается за неоплаченным заказом.
+	ReservationActive ReservationStatus = "active"
+	// ReservationCommitted — заказ оплачен, остаток списан со склада.
+	ReservationCommitted ReservationStatus = "committed"
+	// ReservationReleased — заказ отменён, остаток снова доступен.
+	ReservationReleased ReservationStatus = "released"
+	// ReservationExpired — заказ не оплачен за TTL, резерв снят sweeper'ом.
+	ReservationExpired ReservationStatus = "expired"
+)
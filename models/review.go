@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReviewStatus — состояние модерации отзыва. В рейтинг товара и в публичный
+// список попадают только одобренные отзывы.
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+func (s ReviewStatus) Valid() bool {
+	switch s {
+	case ReviewPending, ReviewApproved, ReviewRejected:
+		return true
+	}
+	return false
+}
+
+var ErrInvalidReview = errors.New("invalid review")
+
+// Review — отзыв о товаре. Verified не хранится: отзыв подтверждён, пока
+// у автора есть доставленный заказ с этим товаром.
+type Review struct {
+	ID                int          `json:"id"`
+	ProductID         int          `json:"product_id"`
+	UserID            int          `json:"user_id"`
+	AuthorName        string       `json:"author_name"`
+	Rating            int          `json:"rating"`
+	Title             string       `json:"title"`
+	Body              string       `json:"body,omitempty"`
+	Verified          bool         `json:"verified"`
+	Status            ReviewStatus `json:"status"`
+	ModerationComment string       `json:"moderation_comment,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+}
+
+// Normalize убирает лишние пробелы в заголовке и тексте.
+func (r *Review) Normalize() {
+	r.Title = strings.TrimSpace(r.Title)
+	r.Body = strings.TrimSpace(r.Body)
+}
+
+func (r Review) Validate() error {
+	if r.Rating < 1 || r.Rating > 5 {
+		return fmt.Errorf("%w: rating must be between 1 and 5", ErrInvalidReview)
+	}
+	if r.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidReview)
+	}
+	return nil
+}
+
+// ReviewQuery — страница отзывов. Status пустой — только одобренные.
+type ReviewQuery struct {
+	ProductID    *int
+	Status       ReviewStatus
+	VerifiedOnly bool
+	Limit        int
+	Offset       int
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+// Address — адрес из адресной книги покупателя.
+type Address struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"-"`
+	Label      string    `json:"label,omitempty"` // «Дом», «Работа»
+	Recipient  string    `json:"recipient"`
+	Phone      string    `json:"phone,omitempty"`
+	Country    string    `json:"country"` // ISO 3166-1 alpha-2
+	City       string    `json:"city"`
+	PostalCode string    `json:"postal_code,omitempty"`
+	Line1      string    `json:"line1"`
+	Line2      string    `json:"line2,omitempty"`
+	IsDefault  bool      `json:"is_default"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Normalize убирает лишние пробелы и приводит код страны к верхнему регистру.
+func (a *Address) Normalize() {
+	for _, f := range []*string{&a.Label, &a.Recipient, &a.Phone, &a.City, &a.PostalCode, &a.Line1, &a.Line2} {
+		*f = strings.TrimSpace(*f)
+	}
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+func (a Address) Validate() error {
+	if a.Recipient == "" || a.City == "" || a.Line1 == "" {
+		return fmt.Errorf("%w: recipient, city and line1 are required", ErrInvalidAddress)
+	}
+	if len(a.Country) != 2 {
+		return fmt.Errorf("%w: country must be a two-letter ISO code", ErrInvalidAddress)
+	}
+	return nil
+}
+
+// ShippingMethod — способ доставки с тарифами по зонам.
+type ShippingMethod struct {
+	ID    int            `json:"id"`
+	Code  string         `json:"code"`
+	Name  string         `json:"name"`
+	Rates []ShippingRate `json:"rates"`
+}
+
+// ShippingRate — тариф способа доставки в зоне для заказов весом
+// до MaxWeightGrams (nil — без ограничения).
+type ShippingRate struct {
+	Zone           string `json:"zone"`
+	MaxWeightGrams *int   `json:"max_weight_grams,omitempty"`
+	Price          Money  `json:"price"`
+	// FreeThreshold — сумма заказа после скидки, начиная с которой доставка
+	// бесплатна; nil — бесплатной доставки нет.
+	FreeThreshold *Money `json:"free_threshold,omitempty"`
+}
+
+// Cost возвращает стоимость доставки заказа на сумму subtotal.
+func (r ShippingRate) Cost(subtotal Money) Money {
+	if r.FreeThreshold != nil && subtotal >= *r.FreeThreshold {
+		return 0
+	}
+	return r.Price
+}
+
+// OrderShipment — доставка заказа с копией адреса на момент оформления.
+type OrderShipment struct {
+	OrderID     int    `json:"order_id"`
+	AddressID   *int   `json:"address_id,omitempty"` // nil, если адрес удалён из книги
+	MethodCode  string `json:"method_code"`
+	MethodName  string `json:"method_name"`
+	Zone        string `json:"zone"`
+	Recipient   string `json:"recipient"`
+	Phone       string `json:"phone,omitempty"`
+	Country     string `json:"country"`
+	City        string `json:"city"`
+	PostalCode  string `json:"postal_code,omitempty"`
+	Line1       string `json:"line1"`
+	Line2       string `json:"line2,omitempty"`
+	WeightGrams int    `json:"weight_grams"`
+	Cost        Money  `json:"cost"`
+}
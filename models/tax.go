@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TaxClass — налоговый класс товара.
+type TaxClass string
+
+const (
+	TaxStandard TaxClass = "standard"
+	TaxReduced  TaxClass = "reduced" // корма
+	TaxExempt   TaxClass = "exempt"  // ветеринарные товары, налог не взимается
+)
+
+// TaxClasses перечисляет классы в порядке, в котором строятся строки налога.
+var TaxClasses = []TaxClass{TaxStandard, TaxReduced, TaxExempt}
+
+func (c TaxClass) Valid() bool {
+	switch c {
+	case TaxStandard, TaxReduced, TaxExempt:
+		return true
+	}
+	return false
+}
+
+var ErrInvalidTaxRegion = errors.New("invalid tax region")
+
+// TaxRegion — налоговый регион со ставками по классам в базисных пунктах
+// (2000 = 20%). Класса нет в Rates — ставка 0.
+type TaxRegion struct {
+	Code             string           `json:"code"`
+	Name             string           `json:"name"`
+	PricesIncludeTax bool             `json:"prices_include_tax"`
+	Rates            map[TaxClass]int `json:"rates"`
+}
+
+func (r TaxRegion) Validate() error {
+	if r.Code == "" || r.Name == "" {
+		return fmt.Errorf("%w: code and name are required", ErrInvalidTaxRegion)
+	}
+	for class, rate := range r.Rates {
+		if !class.Valid() {
+			return fmt.Errorf("%w: unknown tax class %q", ErrInvalidTaxRegion, class)
+		}
+		if rate < 0 || rate > 10000 {
+			return fmt.Errorf("%w: rate for %s must be between 0 and 10000 bp", ErrInvalidTaxRegion, class)
+		}
+		if class == TaxExempt && rate != 0 {
+			return fmt.Errorf("%w: exempt class cannot be taxed", ErrInvalidTaxRegion)
+		}
+	}
+	return nil
+}
+
+// OrderTaxLine — налог заказа по одному классу.
+type OrderTaxLine struct {
+	TaxClass      TaxClass `json:"tax_class"`
+	RateBP        int      `json:"rate_bp"`
+	TaxableAmount Money    `json:"taxable_amount"`
+	Amount        Money    `json:"amount"`
+}
+
+// CalculateTax считает налог по суммам позиций каждого класса. Скидка заказа
+// распределяется между классами пропорционально их суммам, остаток от
+// округления достаётся последнему классу. Если цены региона включают налог,
+// он выделяется из суммы, иначе начисляется сверху.
+func CalculateTax(region TaxRegion, classTotals map[TaxClass]Money, discount Money) []OrderTaxLine {
+	var subtotal Money
+	var classes []TaxClass
+	for _, class := range TaxClasses {
+		if total, ok := classTotals[class]; ok {
+			subtotal += total
+			classes = append(classes, class)
+		}
+	}
+
+	lines := make([]OrderTaxLine, 0, len(classes))
+	remaining := min(discount, subtotal)
+	for i, class := range classes {
+		share := remaining
+		if i < len(classes)-1 && subtotal > 0 {
+			share = min(discount, subtotal).MulFrac(int64(classTotals[class]), int64(subtotal))
+		}
+		remaining -= share
+
+		line := OrderTaxLine{
+			TaxClass:      class,
+			TaxableAmount: classTotals[class] - share,
+		}
+		if class != TaxExempt {
+			line.RateBP = region.Rates[class]
+		}
+		if region.PricesIncludeTax {
+			line.Amount = line.TaxableAmount.MulFrac(int64(line.RateBP), int64(10000+line.RateBP))
+		} else {
+			line.Amount = line.TaxableAmount.MulFrac(int64(line.RateBP), 10000)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}